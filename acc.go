@@ -79,6 +79,27 @@ func TwoSum(a, b float64) (x, y float64) {
 	return
 }
 
+// SafeSub returns a-b, and ok true if the subtraction is exact by Sterbenz's
+// lemma.
+//
+// Sterbenz's lemma states that if a and b are floating point values with
+// b/2 <= a <= 2b, then a-b is exactly representable and so is computed
+// without rounding error.  This holds also for negative values and with
+// gradual underflow.  Ok is true when a and b are finite and satisfy the
+// condition.  A subtraction may happen to be exact even when ok is false.
+//
+// 1 floating point operation, plus comparisons.
+func SafeSub(a, b float64) (x float64, ok bool) {
+	x = a - b
+	if math.IsInf(a, 0) || math.IsInf(b, 0) || a >= 0 != (b >= 0) {
+		return
+	}
+	a, b = math.Abs(a), math.Abs(b)
+	// Doubling is exact or overflows to Inf, either way the test is exact.
+	ok = b <= 2*a && a <= 2*b
+	return
+}
+
 var splitFactor = math.Ldexp(1, 27) + 1
 
 // split splits a into x, y such that x + y = a and both x and y need at most
@@ -107,6 +128,10 @@ func TwoProduct(a, b float64) (x, y float64) {
 
 // Sum2 returns a sum of values in p as if computed in twice the precision
 // of a float64.
+//
+// A zero result is -0 only when p is non-empty and all values are -0, as
+// with IEEE 754 round-to-nearest addition.  If any value in p is NaN, the
+// result is the first NaN in p, with its payload unchanged.
 func Sum2(p []float64) float64 {
 	if len(p) == 0 {
 		return 0.
@@ -117,7 +142,67 @@ func Sum2(p []float64) float64 {
 		s, y = TwoSum(s, x)
 		e += y
 	}
-	return s + e
+	switch s += e; {
+	case s == 0:
+		return signedZero(p)
+	case s != s:
+		if n, ok := firstNaN(p); ok {
+			return n
+		}
+	}
+	return s
+}
+
+// firstNaN returns the first NaN in p, if any.
+func firstNaN(p []float64) (float64, bool) {
+	for _, x := range p {
+		if x != x {
+			return x, true
+		}
+	}
+	return 0, false
+}
+
+// Sum2Err returns a sum and an error bound.
+//
+// The result sum is the same result returned by Sum2, including the sign
+// of a zero and the payload of a NaN.  The result eb is a rigorous error
+// bound, computed as in Dot2Err from the magnitudes of the errors of the
+// TwoSum steps.
+func Sum2Err(p []float64) (sum, eb float64) {
+	var e, a, y float64
+	for _, x := range p {
+		sum, y = TwoSum(sum, x)
+		e += y
+		a += math.Abs(y)
+	}
+	switch sum += e; {
+	case sum == 0:
+		sum = signedZero(p)
+	case sum != sum:
+		if n, ok := firstNaN(p); ok {
+			sum = n
+		}
+	}
+	n := float64(len(p))
+	δ := n * eps / (1 - 2*n*eps)
+	α := eps*math.Abs(sum) + δ*a
+	eb = α / (1 - 2*eps)
+	return
+}
+
+// signedZero returns -0 if p is non-empty and all values of p are -0,
+// and returns +0 otherwise.
+func signedZero(p []float64) float64 {
+	if len(p) == 0 {
+		return 0.
+	}
+	for _, x := range p {
+		if x != 0 || !math.Signbit(x) {
+			return 0.
+		}
+	}
+	return math.Copysign(0, -1)
 }
 
 func vecSum(p []float64) {
@@ -146,10 +231,14 @@ func SumK(p []float64, K int) float64 {
 // a float64.
 //
 // SumKVert computes the same result as SumK but leaves values in p unmodified.
+// K is limited to len(p), and for K < 2 the result is that of Sum.
 func SumKVert(p []float64, K int) float64 {
 	if len(p) < K {
 		K = len(p)
 	}
+	if K < 2 {
+		return Sum(p)
+	}
 	q := make([]float64, K-1)
 	for i, s := range p[:len(q)] {
 		for k, qk := range q[:i] {
@@ -189,6 +278,35 @@ func Dot2(x, y []float64) float64 {
 	return p + s
 }
 
+// Dot2Fast returns a dot product of x and y, skipping the work of Dot2
+// when the dot product is well conditioned.
+//
+// The simple dot product of Dot is computed along with the sum of absolute
+// values of the products.  If that sum is no more than twice the magnitude
+// of the dot product, as when all products have the same sign, the result
+// of Dot is returned.  Its relative error then is at most
+// 2γ(n)/(1-3γ(n)) where γ(n) = n*eps/(1-n*eps), about 2n*eps.  Otherwise
+// the result is that of Dot2.
+//
+// The fast path costs a little more than Dot, the fallback a little more
+// than Dot2.  Note the fast path result does not meet the error bound of
+// Dot2, which is about eps for well conditioned data.  Error bounds assume
+// no underflow in the products.
+//
+// X and y must be of the same length, panic or nonsense results otherwise.
+func Dot2Fast(x, y []float64) float64 {
+	var s, a float64
+	for i, xi := range x {
+		h := xi * y[i]
+		s += h
+		a += math.Abs(h)
+	}
+	if a <= 2*math.Abs(s) {
+		return s
+	}
+	return Dot2(x, y)
+}
+
 // Dot2 returs a dot product and an error bound.
 //
 // The result dot is the same 2-fold precision result returned by Dot2,
@@ -212,6 +330,27 @@ func Dot2Err(x, y []float64) (dot, eb float64) {
 	return
 }
 
+// Dot2Cond returns a dot product and its condition number.
+//
+// The result dot is the same 2-fold precision result returned by Dot2.
+// The result cond is the condition number as computed by
+// CondDot(Dot2, x, y), but computed in the same single pass over x and y.
+func Dot2Cond(x, y []float64) (dot, cond float64) {
+	var p, s, pa, sa, q float64
+	for i, xi := range x {
+		h, r := TwoProduct(xi, y[i])
+		p, q = TwoSum(p, h)
+		s += q + r
+		if h < 0 {
+			h, r = -h, -r
+		}
+		pa, q = TwoSum(pa, h)
+		sa += q + r
+	}
+	dot = p + s
+	return dot, 2 * (pa + sa) / math.Abs(dot)
+}
+
 // DotK returns a dot product of x and y as if computed in K times the
 // precision of a float64.
 func DotK(x, y []float64, K int) float64 {
@@ -277,6 +416,34 @@ func GenDot(n int, c float64) (x, y []float64, d, C float64) {
 	return
 }
 
+// GenSum generates a slice p ill-conditioned for summation.
+//
+// Argument n specifies the length of p, rounded up to an even number.
+// Argument c specifies the approximate condition number for the sum.
+// Values are generated by GenDot and the products transformed error-free
+// with TwoProduct.
+//
+// Result s is the sum of p correctly rounded to a float64, result C is the
+// computed condition number.
+//
+// As with GenDot, GenSum uses the rand package default generator.
+func GenSum(n int, c float64) (p []float64, s, C float64) {
+	x, y, _, _ := GenDot((n+1)/2, c)
+	p = make([]float64, 2*len(x))
+	for i, xi := range x {
+		p[2*i], p[2*i+1] = TwoProduct(xi, y[i])
+	}
+	for i := len(p) - 1; i >= 1; i-- {
+		j := rand.Intn(i + 1)
+		p[i], p[j] = p[j], p[i]
+	}
+	s = NearSum(append([]float64{}, p...))
+	C = CondSum(func(p []float64) float64 {
+		return NearSum(append([]float64{}, p...))
+	}, p)
+	return
+}
+
 // Section:  Algorithms of "Accurate Floating-Point Summation, Part I:
 // Faithful Rounding", http://www.ti3.tu-harburg.de/paper/rump/RuOgOi07I.pdf
 //
@@ -326,10 +493,36 @@ func transform(p []float64) (τ1, τ2 float64) {
 //
 // AccSum is destructive on p.
 //
-// Result is a faithful rounding of the sum of values in p.
+// Result is a faithful rounding of the sum of values in p.  A zero result
+// is -0 only when p is non-empty and all values are -0, as with IEEE 754
+// round-to-nearest addition.  If any value in p is NaN, the result is the
+// first NaN in p, with its payload unchanged.
 func AccSum(p []float64) float64 {
+	if n, ok := firstNaN(p); ok {
+		return n
+	}
 	τ1, τ2 := transform(p)
-	return Sum(p) + τ2 + τ1
+	if res := Sum(p) + τ2 + τ1; res != 0 {
+		return res
+	}
+	// extraction leaves -0 values of p as -0, and leaves no other values as -0.
+	return signedZero(p)
+}
+
+// AccDot returns an accurate dot product of x and y.
+//
+// Products are transformed error-free with TwoProduct into 2*len(x) values
+// whose sum is exactly the dot product, then summed with AccSum.
+//
+// Result is a faithful rounding of the dot product, assuming no underflow
+// in the products.  X and y must be of the same length, panic or nonsense
+// results otherwise.
+func AccDot(x, y []float64) float64 {
+	p := make([]float64, 2*len(x))
+	for i, xi := range x {
+		p[2*i], p[2*i+1] = TwoProduct(xi, y[i])
+	}
+	return AccSum(p)
 }
 
 // Section:  Algorithms of "Accurate Floating-Point Summation, Part II:
@@ -348,18 +541,22 @@ func _ΦSum(Ms float64) float64  { return u * Ms * Ms }
 func _ΦHuge(Ms float64) float64 { return u * Ms * 8 }
 func _ΦSign(Ms float64) float64 { return u * Ms }
 
+// transform3 extracts the sum of ρ and values of p into τ1+τ2, leaving
+// remainders in p.  If p is empty or all zero, τ1 is ρ.  Without that,
+// NearSum loses its correction term when extraction has left no remainders,
+// as can happen with subnormal input, and can round the wrong way.
 func transform3(p []float64, ρ float64, Φ func(Ms float64) float64) (τ1, τ2, σ, Ms float64) {
 	if len(p) == 0 {
-		return
+		return ρ, 0, 0, 0
 	}
 	μ := math.Abs(p[0])
 	for _, x := range p[1:] {
-		if a := math.Abs(x); x > μ {
+		if a := math.Abs(x); a > μ {
 			μ = a
 		}
 	}
 	if μ == 0 {
-		return
+		return ρ, 0, 0, 0
 	}
 	Ms = nextPowerTwo(float64(len(p) + 2))
 	σ = Ms * nextPowerTwo(μ) // "extraction unit"
@@ -377,6 +574,10 @@ func transform3(p []float64, ρ float64, Φ func(Ms float64) float64) (τ1, τ2,
 		}
 		t = τ1
 		if t == 0 {
+			// All extracted so far cancelled exactly.  Extraction is
+			// error-free, so p now sums exactly to the original sum plus ρ
+			// and can be transformed anew, with σ recomputed from the
+			// now smaller values.  This is the restart of the paper.
 			return transform3(p, 0, Φ)
 		}
 		σ *= ϕ
@@ -398,6 +599,14 @@ func transformK(p []float64, ρ float64) (res, R float64) {
 	return
 }
 
+// accSum2 returns the sum of p in 2-fold precision as h+l, as with AccSumK.
+// It is destructive on p.
+func accSum2(p []float64) (h, l float64) {
+	h, r := transformK(p, 0)
+	l, _ = transformK(p, r)
+	return
+}
+
 // AccSumK returns a sum in K-fold precision.
 //
 // AccSumK is destructive on values in p.
@@ -413,6 +622,23 @@ func AccSumK(p []float64, K int) []float64 {
 	return res
 }
 
+// SumRemainder returns an accurate sum of values in p and a remainder.
+//
+// Result sum is the result of AccSum, a faithful rounding of the sum of
+// values in p.  Result rem is a slice of values whose exact sum is exactly
+// the difference between the exact sum of p and the result sum.  The
+// remainder can be carried into further computation in greater precision.
+//
+// SumRemainder is not destructive on p.
+func SumRemainder(p []float64) (sum float64, rem []float64) {
+	rem = append([]float64{}, p...)
+	sum, R := transformK(rem, 0)
+	if sum == 0 {
+		sum = signedZero(p)
+	}
+	return sum, append(rem, R)
+}
+
 // DownSum returns an accurate sum of values in p, rounded down to the nearest
 // float64.
 func DownSum(p []float64) float64 {
@@ -433,13 +659,41 @@ func UpSum(p []float64) float64 {
 	return res
 }
 
+// SumInterval returns the tightest float64 interval containing the sum of
+// values in p.
+//
+// If the exact sum is a float64, lo and hi both equal it.  Otherwise lo and
+// hi are the adjacent float64s below and above the exact sum, the results
+// of DownSum and UpSum.  SumInterval computes both bounds together, on a
+// copy of p, so it is not destructive on p.
+func SumInterval(p []float64) (lo, hi float64) {
+	q := append([]float64{}, p...)
+	res, r := transformK(q, 0)
+	switch δ, _ := transformK(q, r); {
+	case δ < 0:
+		return math.Nextafter(res, math.Inf(-1)), res
+	case δ > 0:
+		return res, math.Nextafter(res, math.Inf(1))
+	}
+	return res, res
+}
+
 // NearSum returns an accurate sum of values in p, rounded to the nearest
 // float64.
+//
+// Signed zeros and NaNs are handled as with AccSum.  NearSum is destructive
+// on p.
 func NearSum(p []float64) float64 {
+	if n, ok := firstNaN(p); ok {
+		return n
+	}
 	τ1, τ2 := transform(p)
 	τ2ʹ := τ2 + Sum(p)
 	res, δ := FastTwoSum(τ1, τ2ʹ)
 	if δ == 0 {
+		if res == 0 {
+			return signedZero(p)
+		}
 		return res
 	}
 	R := τ2 - (res - τ1)
@@ -466,6 +720,44 @@ func NearSum(p []float64) float64 {
 	return res + δʹ
 }
 
+// RoundingMode specifies rounding for NearSumMode.
+type RoundingMode int
+
+// Rounding modes for NearSumMode.
+const (
+	ToNearestEven RoundingMode = iota // round to nearest, ties to even
+	TowardZero                        // round toward zero
+	Up                                // round toward +Inf
+	Down                              // round toward -Inf
+)
+
+// NearSumMode returns an accurate sum of values in p, rounded according to
+// mode.
+//
+// ToNearestEven gives the result of NearSum, Up the result of UpSum, and
+// Down the result of DownSum.  For TowardZero, DownSum is computed on a copy
+// of p and UpSum used instead if the sum is negative.
+//
+// Ties in ToNearestEven rounding are broken as with IEEE 754 float64
+// addition.  That is, when the exact sum is halfway between two float64s,
+// the result is the one with an even least significant bit.
+//
+// Like NearSum, NearSumMode is destructive on p.
+func NearSumMode(p []float64, mode RoundingMode) float64 {
+	switch mode {
+	case TowardZero:
+		if d := DownSum(append([]float64{}, p...)); d >= 0 {
+			return d
+		}
+		return UpSum(p)
+	case Up:
+		return UpSum(p)
+	case Down:
+		return DownSum(p)
+	}
+	return NearSum(p)
+}
+
 func AccSumHuge(p []float64) float64 {
 	τ1, τ2, σ, Ms := transform3(p, 0, _ΦHuge)
 	if σ <= minPos {
@@ -497,7 +789,7 @@ func PrecSum(p []float64, K int) float64 {
 	}
 	μ := math.Abs(p[0])
 	for _, x := range p[1:] {
-		if a := math.Abs(x); x > μ {
+		if a := math.Abs(x); a > μ {
 			μ = a
 		}
 	}
@@ -505,11 +797,11 @@ func PrecSum(p []float64, K int) float64 {
 	if μ == 0 {
 		return 0.
 	}
-	σ0 := nextPowerTwo(μ)
+	Ms := nextPowerTwo(float64(len(p) + 2))
+	σ0 := Ms * nextPowerTwo(μ)
 	if math.IsInf(σ0, 0) {
 		return σ0
 	}
-	Ms := nextPowerTwo(float64(len(p) + 2))
 	M := math.Log2(Ms)
 	ϕ := Ms * u
 	// len(σ) is L in paper and reference code.  also, paper and reference code
@@ -553,6 +845,122 @@ func PrecSum(p []float64, K int) float64 {
 	return sum + e + π
 }
 
+// PrecSumTol returns an accurate sum of values in p with relative error
+// not exceeding relTol.
+//
+// The condition number of the sum is estimated and used to choose the
+// smallest fold count K for PrecSum such that 2^(-53*K) * cond <= relTol,
+// that is, K = ceil(log(cond/relTol) / log(1/eps)) where eps = 2^-53.
+// The condition number is estimated from Sum2 together with an error bound
+// for Sum2.  Only when the sum is too ill-conditioned for Sum2 to bound it
+// is the condition number computed more expensively with AccSum.
+//
+// As with PrecSum, the result is a faithful rounding of the sum even if
+// relTol is less than the relative rounding error unit.  A relTol less than
+// eps is treated as eps.  PrecSumTol panics if relTol is not positive.
+func PrecSumTol(p []float64, relTol float64) float64 {
+	if !(relTol > 0) {
+		panic(fmt.Sprintf("relTol = %g, want > 0", relTol))
+	}
+	var s, e, q, abs float64
+	for _, x := range p {
+		s, q = TwoSum(s, x)
+		e += q
+		abs += math.Abs(x)
+	}
+	s += e
+	// A priori bound on the error of Sum2, see Proposition 4.5 of
+	// "Accurate Sum and Dot Product."
+	n := float64(len(p))
+	γ := n * eps / (1 - n*eps)
+	eb := eps*math.Abs(s) + γ*γ*abs
+	var cond float64
+	if math.Abs(s) > 2*eb {
+		cond = abs / (math.Abs(s) - eb)
+	} else {
+		s = AccSum(append([]float64{}, p...))
+		if s == 0 {
+			return 0.
+		}
+		cond = abs / math.Abs(s) * (1 + 2*eps)
+	}
+	// Logs are subtracted rather than divided so cond/relTol cannot
+	// overflow.  K is limited to what covers the whole exponent range.
+	k := math.Ceil((math.Log(cond) - math.Log(math.Max(relTol, eps))) /
+		math.Log(invEps))
+	const kMax = (2*EMax + 2*P) / P
+	if !(k <= kMax) {
+		k = kMax
+	}
+	K := int(k)
+	if K < 1 {
+		K = 1
+	}
+	return PrecSum(p, K)
+}
+
+// DotKTol returns an accurate dot product of x and y with relative error
+// not exceeding relTol.
+//
+// The condition number of the dot product is estimated and used to choose
+// the smallest K for DotK such that the error bound of DotK,
+// eps + 2γ(4n-2)² + γ(4n-2)^K * cond/2, is within relTol.  As with
+// PrecSumTol, the condition number is estimated from Dot2 together with a
+// rigorous error bound, and only when the dot product is too
+// ill-conditioned for that is the denominator computed with AccDot.
+//
+// K is limited to what covers the whole exponent range, which is also the
+// K used when the sum of absolute values of the products overflows so that
+// no condition number can be computed.  When no K meets relTol, because
+// 2γ(4n-2)² alone exceeds it or the limit on K is reached, the result is
+// instead the faithful rounding returned by AccDot, which has relative
+// error less than 2eps.  DotKTol panics if relTol is less than 2eps = 2^-52.
+// X and y must be of the same length, panic or nonsense results otherwise.
+func DotKTol(x, y []float64, relTol float64) float64 {
+	if !(relTol >= 2*eps) {
+		panic(fmt.Sprintf("relTol = %g, want >= 2^-52", relTol))
+	}
+	if len(x) == 0 {
+		return 0.
+	}
+	n := float64(4*len(x) - 2)
+	γ := n * eps / (1 - n*eps)
+	tol := relTol - eps - 2*γ*γ
+	var abs float64
+	for i, xi := range x {
+		abs += math.Abs(xi * y[i])
+	}
+	abs *= 1 + 2*float64(len(x))*eps
+	const kMax = (2*EMax + 2*P) / P
+	switch {
+	case !(tol > 0):
+		return AccDot(x, y)
+	case math.IsInf(abs, 0):
+		return DotK(x, y, kMax)
+	}
+	dot, eb := Dot2Err(x, y)
+	var cond float64
+	if math.Abs(dot) > 2*eb {
+		cond = 2 * abs / (math.Abs(dot) - eb)
+	} else {
+		d := AccDot(x, y)
+		if d == 0 {
+			return 0.
+		}
+		cond = 2 * abs / math.Abs(d) * (1 + 2*eps)
+	}
+	// As in PrecSumTol, logs are subtracted so cond/tol cannot overflow.
+	k := math.Ceil((math.Log(cond) - math.Log(2*tol)) / -math.Log(γ))
+	if !(k <= kMax) {
+		return AccDot(x, y)
+	}
+	K := int(k)
+	if K < 2 {
+		K = 2
+	}
+	return DotK(x, y, K)
+}
+
 // nextPowerTwo returns the smallest power of 2 not less than abs(p).
 //
 // Result is computed in 4 floating point operations.
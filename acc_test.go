@@ -6,7 +6,9 @@ package accsum_test
 import (
 	"fmt"
 	"math"
+	"math/big"
 	"math/rand"
+	"testing"
 
 	"github.com/soniakeys/accsum"
 )
@@ -344,3 +346,480 @@ func ExampleUpSum() {
 	// UpSum:      1.0000000000147543e+20
 	// Next lower: 1.0000000000147541e+20
 }
+
+func TestSafeSub(t *testing.T) {
+	two := big.NewFloat(2)
+	for i := 0; i < 100000; i++ {
+		a := math.Ldexp(rand.Float64(), rand.Intn(2098)-1074)
+		b := a * (.3 + 2.4*rand.Float64())
+		if rand.Intn(2) == 0 {
+			a, b = -a, -b
+		}
+		if i%5 == 0 {
+			b = -b
+		}
+		x, ok := accsum.SafeSub(a, b)
+		var ba, bb, a2, b2, d big.Float
+		ba.SetFloat64(a)
+		bb.SetFloat64(b)
+		a2.Mul(&ba, two).Abs(&a2)
+		b2.Mul(&bb, two).Abs(&b2)
+		ba.Abs(&ba)
+		bb.Abs(&bb)
+		want := (a >= 0) == (b >= 0) && bb.Cmp(&a2) <= 0 && ba.Cmp(&b2) <= 0
+		if math.IsInf(b, 0) {
+			want = false
+		}
+		if ok != want {
+			t.Fatalf("SafeSub(%g, %g) ok = %t, want %t", a, b, ok, want)
+		}
+		if !ok {
+			continue
+		}
+		d.SetPrec(2100).Sub(ba.SetFloat64(a), bb.SetFloat64(b))
+		if d.Cmp(big.NewFloat(x)) != 0 {
+			t.Fatalf("SafeSub(%g, %g) = %g, inexact", a, b, x)
+		}
+	}
+	if _, ok := accsum.SafeSub(math.Inf(1), math.Inf(1)); ok {
+		t.Fatal("SafeSub(+Inf, +Inf) ok = true")
+	}
+	if _, ok := accsum.SafeSub(math.NaN(), 1); ok {
+		t.Fatal("SafeSub(NaN, 1) ok = true")
+	}
+}
+
+func TestSumSubnormal(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		p := make([]float64, 1000+rand.Intn(4000))
+		for j := range p {
+			p[j] = math.Float64frombits(uint64(rand.Int63n(1 << 52)))
+			if rand.Intn(2) == 0 {
+				p[j] = -p[j]
+			}
+		}
+		// a normal pair cancelling exactly
+		p = append(p, 3e-300, -3e-300)
+		want := accsum.ExactSumSorted(p)
+		if got := accsum.NearSum(append([]float64{}, p...)); got != want {
+			t.Fatalf("NearSum = %.17g, want %.17g", got, want)
+		}
+		got := accsum.AccSum(append([]float64{}, p...))
+		if got != want && got != math.Nextafter(want, got) {
+			t.Fatalf("AccSum = %.17g, not faithful to %.17g", got, want)
+		}
+	}
+}
+
+func TestTransformRestart(t *testing.T) {
+	// Large values cancel exactly in the first extraction, leaving a zero
+	// running total and smaller values still to be summed.
+	for i := 0; i < 200; i++ {
+		p, _, _ := accsum.GenSum(50, 1e20)
+		for j := range p {
+			p[j] = math.Ldexp(p[j], -40)
+		}
+		for j := 0; j < 3; j++ {
+			x := math.Ldexp(rand.Float64(), 20+rand.Intn(20))
+			p = append(p, x, -x)
+		}
+		rand.Shuffle(len(p), func(i, j int) { p[i], p[j] = p[j], p[i] })
+		want := accsum.ExactSumSorted(p)
+		got := accsum.AccSum(append([]float64{}, p...))
+		if got != want && got != math.Nextafter(want, got) {
+			t.Fatalf("AccSum = %.17g, not faithful to %.17g", got, want)
+		}
+		if got := accsum.NearSum(append([]float64{}, p...)); got != want {
+			t.Fatalf("NearSum = %.17g, want %.17g", got, want)
+		}
+	}
+	p := []float64{0x1p30, -0x1p30, 0x1p-30, -0x1p-60, 0x1p-70}
+	if got, want := accsum.AccSum(p), 0x1p-30-0x1p-60+0x1p-70; got != want {
+		t.Fatalf("AccSum = %.17g, want %.17g", got, want)
+	}
+}
+
+func TestPrecSumTol(t *testing.T) {
+	for _, c := range []float64{1e5, 1e15, 1e25, 1e35} {
+		for _, relTol := range []float64{1e-6, 1e-12, 1e-15} {
+			p, s, _ := accsum.GenSum(100, c)
+			got := accsum.PrecSumTol(p, relTol)
+			if e := math.Abs((got - s) / s); e > relTol {
+				t.Fatalf("cond %g, relTol %g: PrecSumTol = %.17g, want %.17g"+
+					" relative error %g", c, relTol, got, s, e)
+			}
+		}
+	}
+	// relTol below eps gives a faithful result
+	for _, relTol := range []float64{1e-20, 1e-300, math.SmallestNonzeroFloat64} {
+		p, _, _ := accsum.GenSum(100, 1e30)
+		want := accsum.ExactSumSorted(p)
+		got := accsum.PrecSumTol(p, relTol)
+		if got != want && got != math.Nextafter(want, got) {
+			t.Fatalf("relTol %g: PrecSumTol = %.17g, not faithful to %.17g",
+				relTol, got, want)
+		}
+	}
+	for _, relTol := range []float64{0, -1, math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("PrecSumTol with relTol %g did not panic", relTol)
+				}
+			}()
+			accsum.PrecSumTol([]float64{1}, relTol)
+		}()
+	}
+}
+
+func TestDotKTol(t *testing.T) {
+	for _, c := range []float64{1e5, 1e15, 1e25, 1e35, 1e50} {
+		for _, relTol := range []float64{1e-6, 1e-12, 1e-15,
+			0x1.8p-52, 0x1p-52} {
+			x, y, _, _ := accsum.GenDot(100, c)
+			want := exactDot(x, y)
+			got := accsum.DotKTol(x, y, relTol)
+			if e := math.Abs((got - want) / want); e > relTol {
+				t.Fatalf("cond %g, relTol %g: DotKTol = %.17g, want %.17g"+
+					" relative error %g", c, relTol, got, want, e)
+			}
+		}
+	}
+	// sum of absolute values of products overflows
+	for _, tc := range []struct{ x, y []float64 }{
+		{[]float64{0x1p600, -0x1p600, 3}, []float64{0x1.8p423, 0x1.8p423, .5}},
+		{[]float64{0x1p600, -0x1p600, 3},
+			[]float64{0x1.8p423, 0x1.7ffffffffffffp423, .5}},
+	} {
+		want := exactDot(tc.x, tc.y)
+		got := accsum.DotKTol(tc.x, tc.y, 1e-15)
+		if e := math.Abs((got - want) / want); e > 1e-15 {
+			t.Fatalf("DotKTol(%g, %g) = %.17g, want %.17g",
+				tc.x, tc.y, got, want)
+		}
+	}
+	for _, relTol := range []float64{0x1.fffffffffffffp-53, 0, math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("DotKTol with relTol %g did not panic", relTol)
+				}
+			}()
+			accsum.DotKTol([]float64{1}, []float64{1}, relTol)
+		}()
+	}
+}
+
+func TestSignedZero(t *testing.T) {
+	nz := math.Copysign(0, -1)
+	for _, tc := range []struct {
+		p   []float64
+		neg bool
+	}{
+		{nil, false},
+		{[]float64{0}, false},
+		{[]float64{nz}, true},
+		{[]float64{nz, nz, nz}, true},
+		{[]float64{nz, 0}, false},
+		{[]float64{1, -1}, false},
+		{[]float64{-1, 1}, false},
+		{[]float64{nz, -1, 1, nz}, false},
+		{[]float64{1e20, nz, -1e20}, false},
+	} {
+		for _, f := range []struct {
+			name string
+			f    func([]float64) float64
+		}{
+			{"Sum2", accsum.Sum2},
+			{"AccSum", accsum.AccSum},
+			{"NearSum", accsum.NearSum},
+		} {
+			s := f.f(append([]float64{}, tc.p...))
+			if s != 0 || math.Signbit(s) != tc.neg {
+				t.Fatalf("%s(%v) = %g, want zero with sign bit %t",
+					f.name, tc.p, s, tc.neg)
+			}
+		}
+	}
+}
+
+// exactDot returns the dot product of x and y correctly rounded.
+func exactDot(x, y []float64) float64 {
+	p := make([]float64, 0, 2*len(x))
+	for i, xi := range x {
+		h, r := accsum.TwoProduct(xi, y[i])
+		p = append(p, h, r)
+	}
+	return accsum.ExactSumSorted(p)
+}
+
+func TestAccDot(t *testing.T) {
+	for _, c := range []float64{1e5, 1e15, 1e25, 1e35, 1e50} {
+		x, y, _, _ := accsum.GenDot(100, c)
+		want := exactDot(x, y)
+		if got := accsum.AccDot(x, y); math.Abs(got-want) > ulp(want) {
+			t.Fatalf("cond %g: AccDot = %.17g, want %.17g", c, got, want)
+		}
+	}
+}
+
+func BenchmarkAccDot(b *testing.B) {
+	x, y, _, _ := accsum.GenDot(1000, 1e30)
+	for i := 0; i < b.N; i++ {
+		accsum.AccDot(x, y)
+	}
+}
+
+func BenchmarkDotK3(b *testing.B) {
+	x, y, _, _ := accsum.GenDot(1000, 1e30)
+	for i := 0; i < b.N; i++ {
+		accsum.DotK(x, y, 3)
+	}
+}
+
+func TestNearSumMode(t *testing.T) {
+	h := math.Ldexp(1, -53) // half an ulp of 1
+	for _, tc := range []struct {
+		p          []float64
+		near, zero float64
+		up, down   float64
+	}{
+		// ties, halfway between two float64s
+		{[]float64{1, h}, 1, 1, 1 + 2*h, 1},
+		{[]float64{1 + 2*h, h}, 1 + 4*h, 1 + 2*h, 1 + 4*h, 1 + 2*h},
+		{[]float64{1e20, 1, h, -1e20}, 1, 1, 1 + 2*h, 1},
+		{[]float64{-1e20, -1, -h, 1e20}, -1, -1, -1, -1 - 2*h},
+		{[]float64{-1 - 2*h, -h, 1e-300, -1e-300}, -1 - 4*h, -1 - 2*h,
+			-1 - 2*h, -1 - 4*h},
+		// not ties
+		{[]float64{1, h, h / 4}, 1 + 2*h, 1, 1 + 2*h, 1},
+		{[]float64{-1, -h, h / 4}, -1, -1, -1, -1 - 2*h},
+		{[]float64{3, -3}, 0, 0, 0, 0},
+	} {
+		for _, m := range []struct {
+			mode accsum.RoundingMode
+			want float64
+		}{
+			{accsum.ToNearestEven, tc.near},
+			{accsum.TowardZero, tc.zero},
+			{accsum.Up, tc.up},
+			{accsum.Down, tc.down},
+		} {
+			p := append([]float64{}, tc.p...)
+			if got := accsum.NearSumMode(p, m.mode); got != m.want {
+				t.Fatalf("NearSumMode(%v, %d) = %.17g, want %.17g",
+					tc.p, m.mode, got, m.want)
+			}
+		}
+		if len(tc.p) == 2 {
+			// IEEE addition rounds ties to even
+			p := append([]float64{}, tc.p...)
+			if got, want := accsum.NearSum(p), tc.p[0]+tc.p[1]; got != want {
+				t.Fatalf("NearSum(%v) = %.17g, want %.17g", tc.p, got, want)
+			}
+		}
+	}
+}
+
+func TestNearSumModeRandom(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		p := randSlice(50)
+		p = append(p, -p[0], -p[1], -p[2])
+		exact := new(big.Float).SetPrec(2200)
+		var bx big.Float
+		for _, x := range p {
+			exact.Add(exact, bx.SetFloat64(x))
+		}
+		for _, m := range []struct {
+			mode accsum.RoundingMode
+			big  big.RoundingMode
+		}{
+			{accsum.ToNearestEven, big.ToNearestEven},
+			{accsum.TowardZero, big.ToZero},
+			{accsum.Up, big.ToPositiveInf},
+			{accsum.Down, big.ToNegativeInf},
+		} {
+			r := new(big.Float).SetPrec(accsum.P).SetMode(m.big).Set(exact)
+			want, _ := r.Float64()
+			got := accsum.NearSumMode(append([]float64{}, p...), m.mode)
+			if got != want {
+				t.Fatalf("NearSumMode(%v, %d) = %.17g, want %.17g",
+					p, m.mode, got, want)
+			}
+		}
+	}
+}
+
+func TestSum2Err(t *testing.T) {
+	check := func(p []float64) {
+		sum, eb := accsum.Sum2Err(p)
+		if s2 := accsum.Sum2(p); math.Float64bits(sum) != math.Float64bits(s2) {
+			t.Fatalf("Sum2Err sum = %.17g, Sum2 = %.17g", sum, s2)
+		}
+		exact := new(big.Float).SetPrec(2200)
+		var bx big.Float
+		for _, x := range p {
+			exact.Add(exact, bx.SetFloat64(x))
+		}
+		lo := new(big.Float).SetPrec(2200).SetFloat64(sum)
+		hi := new(big.Float).Copy(lo)
+		lo.Sub(lo, bx.SetFloat64(eb))
+		hi.Add(hi, &bx)
+		if exact.Cmp(lo) < 0 || exact.Cmp(hi) > 0 {
+			t.Fatalf("exact sum %g not within %.17g ± %g", exact, sum, eb)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		check(randSlice(1 + rand.Intn(1000)))
+	}
+	for _, c := range []float64{1e10, 1e20, 1e30, 1e40} {
+		p, _, _ := accsum.GenSum(1000, c)
+		check(p)
+	}
+	// sum matches Sum2 for signed zeros and NaN payloads too
+	nz := math.Copysign(0, -1)
+	n1 := math.Float64frombits(0x7ff8000000000123)
+	n2 := math.Float64frombits(0xfff8000000000456)
+	for _, p := range [][]float64{
+		{nz},
+		{nz, nz},
+		{1, n1, 2},
+		{1e20, n1, -1e20, n2},
+		{math.Inf(1), n1, math.Inf(-1)},
+		{math.Inf(1), math.Inf(-1)},
+	} {
+		sum, _ := accsum.Sum2Err(p)
+		if s2 := accsum.Sum2(p); math.Float64bits(sum) != math.Float64bits(s2) {
+			t.Fatalf("Sum2Err(%v) sum = %#x, Sum2 = %#x",
+				p, math.Float64bits(sum), math.Float64bits(s2))
+		}
+	}
+}
+
+func TestSumRemainder(t *testing.T) {
+	bigSum := func(p []float64) *big.Float {
+		s := new(big.Float).SetPrec(2200)
+		var bx big.Float
+		for _, x := range p {
+			s.Add(s, bx.SetFloat64(x))
+		}
+		return s
+	}
+	for _, c := range []float64{1, 1e10, 1e20, 1e30, 1e40} {
+		p, _, _ := accsum.GenSum(100, c)
+		sum, rem := accsum.SumRemainder(p)
+		if want := accsum.AccSum(append([]float64{}, p...)); sum != want {
+			t.Fatalf("SumRemainder sum = %.17g, want %.17g", sum, want)
+		}
+		if got, want := bigSum(append(rem, sum)), bigSum(p); got.Cmp(want) != 0 {
+			t.Fatalf("sum + remainder = %g, want %g", got, want)
+		}
+	}
+}
+
+func TestNaNPayload(t *testing.T) {
+	n1 := math.Float64frombits(0x7ff8000000000123)
+	n2 := math.Float64frombits(0xfff8000000000456)
+	for _, p := range [][]float64{
+		{n1},
+		{1, n1, 2},
+		{1e20, n1, -1e20, n2},
+		{math.Inf(1), n1, math.Inf(-1)},
+	} {
+		for _, f := range []struct {
+			name string
+			f    func([]float64) float64
+		}{
+			{"Sum2", accsum.Sum2},
+			{"AccSum", accsum.AccSum},
+			{"NearSum", accsum.NearSum},
+		} {
+			s := f.f(append([]float64{}, p...))
+			if b := math.Float64bits(s); b != math.Float64bits(n1) {
+				t.Fatalf("%s(%v) = %#x, want %#x",
+					f.name, p, b, math.Float64bits(n1))
+			}
+		}
+	}
+}
+
+func TestDot2Cond(t *testing.T) {
+	for _, c := range []float64{1e3, 1e10, 1e20} {
+		x, y, _, _ := accsum.GenDot(100, c)
+		dot, cond := accsum.Dot2Cond(x, y)
+		if d := accsum.Dot2(x, y); dot != d {
+			t.Fatalf("Dot2Cond dot = %.17g, want %.17g", dot, d)
+		}
+		if want := accsum.CondDot(accsum.Dot2, x, y); cond != want {
+			t.Fatalf("Dot2Cond cond = %.17g, want %.17g", cond, want)
+		}
+	}
+}
+
+func TestDot2Fast(t *testing.T) {
+	// relative error bound of the fast path
+	bound := func(n int) float64 {
+		γ := float64(n) * 0x1p-53 / (1 - float64(n)*0x1p-53)
+		return 2 * γ / (1 - 3*γ)
+	}
+	// well conditioned, all products positive
+	for i := 0; i < 20; i++ {
+		x := make([]float64, 1000)
+		y := make([]float64, len(x))
+		for j := range x {
+			x[j] = math.Ldexp(rand.Float64(), rand.Intn(20))
+			y[j] = math.Ldexp(rand.Float64(), rand.Intn(20))
+		}
+		got := accsum.Dot2Fast(x, y)
+		if d := accsum.Dot(x, y); got != d {
+			t.Fatalf("Dot2Fast = %.17g, want Dot = %.17g", got, d)
+		}
+		want := exactDot(x, y)
+		if e := math.Abs((got - want) / want); e > bound(len(x)) {
+			t.Fatalf("Dot2Fast = %.17g, want %.17g, relative error %g",
+				got, want, e)
+		}
+	}
+	// ill conditioned falls back to Dot2
+	for _, c := range []float64{1e3, 1e10, 1e20, 1e30} {
+		for i := 0; i < 20; i++ {
+			x, y, _, _ := accsum.GenDot(100, c)
+			if got, want := accsum.Dot2Fast(x, y), accsum.Dot2(x, y); got != want {
+				t.Fatalf("cond %g: Dot2Fast = %.17g, Dot2 = %.17g", c, got, want)
+			}
+		}
+	}
+	if d := accsum.Dot2Fast(nil, nil); d != 0 {
+		t.Fatalf("Dot2Fast(nil, nil) = %g, want 0", d)
+	}
+}
+
+func TestSumInterval(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		p, _, _ := accsum.GenSum(100, math.Pow(10, float64(rand.Intn(40))))
+		if i%10 == 0 {
+			p = []float64{1e20, float64(i), -1e20} // exact
+		}
+		lo, hi := accsum.SumInterval(p)
+		exact := new(big.Float).SetPrec(2200)
+		var bx big.Float
+		for _, x := range p {
+			exact.Add(exact, bx.SetFloat64(x))
+		}
+		if exact.Cmp(bx.SetFloat64(lo)) < 0 || exact.Cmp(bx.SetFloat64(hi)) > 0 {
+			t.Fatalf("exact sum %g not within [%.17g, %.17g]", exact, lo, hi)
+		}
+		if hi != lo && hi != math.Nextafter(lo, math.Inf(1)) {
+			t.Fatalf("interval [%.17g, %.17g] wider than one ulp", lo, hi)
+		}
+		if f, _ := exact.Float64(); exact.Cmp(bx.SetFloat64(f)) == 0 && lo != hi {
+			t.Fatalf("interval [%.17g, %.17g], want single value %.17g", lo, hi, f)
+		}
+		d := accsum.DownSum(append([]float64{}, p...))
+		u := accsum.UpSum(append([]float64{}, p...))
+		if lo != d || hi != u {
+			t.Fatalf("SumInterval = [%.17g, %.17g], DownSum, UpSum = %.17g, %.17g",
+				lo, hi, d, u)
+		}
+	}
+}
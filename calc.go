@@ -0,0 +1,118 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum
+
+// Calc.go:  Numerical integration and differentiation of sampled data.
+
+import "fmt"
+
+// Trapz returns the integral of sampled data by the trapezoidal rule,
+// the sum of (x[i+1]-x[i]) * (y[i]+y[i+1]) / 2.
+//
+// Abscissas x may be non-uniformly spaced.  The differences, sums, and
+// products of each term are formed with TwoSum and TwoProduct, and terms are
+// summed as if in twice the precision of a float64.
+//
+// Trapz panics if x and y differ in length or have fewer than 2 points.
+func Trapz(x, y []float64) float64 {
+	if len(y) != len(x) {
+		panic(fmt.Sprintf("len(y) = %d, want len(x) = %d", len(y), len(x)))
+	}
+	if len(x) < 2 {
+		panic(fmt.Sprintf("len(x) = %d, need at least 2 points", len(x)))
+	}
+	var s, e, q float64
+	for i := 1; i < len(x); i++ {
+		d, de := TwoSum(x[i], -x[i-1])
+		h, he := TwoSum(y[i-1], y[i])
+		a, ae := TwoProduct(d, h)
+		s, q = TwoSum(s, a)
+		e += q + (ae + d*he + de*h)
+	}
+	return (s + e) / 2
+}
+
+// Simpson returns the integral of sampled data by Simpson's rule.
+//
+// Abscissas x must be uniformly spaced, although only the first and last
+// values are used, to determine the spacing.  The number of points must be
+// odd.  The samples are weighted 1, 4, 2, 4, ..., 2, 4, 1; these weights are
+// powers of two so weighting is exact, and weighted samples are summed as if
+// in twice the precision of a float64.  The final scaling by the spacing is
+// applied to the double-length sum before rounding.
+//
+// Simpson panics if x and y differ in length or if the number of points is
+// even or less than 3.
+func Simpson(x, y []float64) float64 {
+	if len(y) != len(x) {
+		panic(fmt.Sprintf("len(y) = %d, want len(x) = %d", len(y), len(x)))
+	}
+	n := len(x)
+	if n < 3 || n%2 == 0 {
+		panic(fmt.Sprintf("len(x) = %d, need an odd number of points >= 3", n))
+	}
+	s, e := y[0], 0.
+	var q float64
+	for i := 1; i < n-1; i++ {
+		w := 4.
+		if i%2 == 0 {
+			w = 2
+		}
+		s, q = TwoSum(s, w*y[i])
+		e += q
+	}
+	s, q = TwoSum(s, y[n-1])
+	e += q
+	d, de := TwoSum(x[n-1], -x[0])
+	p, pe := TwoProduct(s, d)
+	pe += e*d + s*de
+	return ddDiv(p, pe, float64(3*(n-1)), 0)
+}
+
+// SumGrid returns the sum of f(a + i*h) for i = 0 to n-1, without
+// materializing the samples.
+//
+// Each grid point is formed from a, i, and h directly, the product with
+// TwoProduct and the sum with TwoSum, so that it is rounded only once.
+// Points thus do not drift as they would by repeatedly adding h.  Values of
+// f are summed as if in twice the precision of a float64.  For n <= 0 the
+// result is 0.
+func SumGrid(f func(float64) float64, a, h float64, n int) float64 {
+	var s, e, q float64
+	for i := 0; i < n; i++ {
+		ph, pl := TwoProduct(float64(i), h)
+		x, xl := TwoSum(a, ph)
+		s, q = TwoSum(s, f(x+(xl+pl)))
+		e += q
+	}
+	return s + e
+}
+
+// FiniteDiffSum returns finite difference estimates of the derivative of
+// data y sampled at uniform spacing h.
+//
+// Interior elements of the result are the central differences
+// (y[i+1]-y[i-1])/(2h), and the first and last are the one-sided differences
+// (y[1]-y[0])/h and (y[n-1]-y[n-2])/h.  Each difference of samples is formed
+// error-free with TwoSum and the double-length difference is divided before
+// rounding, so each element is nearly correctly rounded even when y values
+// are close and differences of the rounded samples would lose accuracy.
+//
+// FiniteDiffSum panics if y has fewer than 2 points.
+func FiniteDiffSum(y []float64, h float64) []float64 {
+	n := len(y)
+	if n < 2 {
+		panic(fmt.Sprintf("len(y) = %d, need at least 2 points", n))
+	}
+	d := make([]float64, n)
+	dh, dl := TwoSum(y[1], -y[0])
+	d[0] = ddDiv(dh, dl, h, 0)
+	for i := 1; i < n-1; i++ {
+		dh, dl = TwoSum(y[i+1], -y[i-1])
+		d[i] = ddDiv(dh, dl, 2*h, 0)
+	}
+	dh, dl = TwoSum(y[n-1], -y[n-2])
+	d[n-1] = ddDiv(dh, dl, h, 0)
+	return d
+}
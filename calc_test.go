@@ -0,0 +1,105 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum_test
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/soniakeys/accsum"
+)
+
+func TestTrapz(t *testing.T) {
+	n := 10000
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = 1e4 + rand.Float64()*10
+	}
+	sort.Float64s(x)
+	y := make([]float64, n)
+	for i, xi := range x {
+		y[i] = 1e3 + math.Sin(xi)
+	}
+	ref := new(big.Float).SetPrec(500)
+	var d, h, a big.Float
+	d.SetPrec(500)
+	h.SetPrec(500)
+	a.SetPrec(500)
+	for i := 1; i < n; i++ {
+		d.Sub(big.NewFloat(x[i]), big.NewFloat(x[i-1]))
+		h.Add(big.NewFloat(y[i]), big.NewFloat(y[i-1]))
+		ref.Add(ref, a.Mul(&d, &h))
+	}
+	want, _ := ref.Quo(ref, big.NewFloat(2)).Float64()
+	if got := accsum.Trapz(x, y); math.Abs(got-want) > ulp(want) {
+		t.Fatalf("Trapz = %.17g, want %.17g", got, want)
+	}
+}
+
+func TestSimpson(t *testing.T) {
+	// x^3 - 2x^2 + x + 5, integrated exactly by Simpson's rule
+	n := 129
+	x := make([]float64, n)
+	y := make([]float64, n)
+	for i := range x {
+		xi := float64(i) / 64
+		x[i] = xi
+		y[i] = xi*xi*xi - 2*xi*xi + xi + 5
+	}
+	want := 32. / 3
+	if got := accsum.Simpson(x, y); math.Abs(got-want) > ulp(want) {
+		t.Fatalf("Simpson = %.17g, want %.17g", got, want)
+	}
+}
+
+func TestSumGrid(t *testing.T) {
+	// Σ (a + i*h) = n*a + h*n*(n-1)/2, here 1e6*.3 + .1*(1e6*999999/2)
+	const n = 1000000
+	want := 300000. + 49999950000
+	id := func(x float64) float64 { return x }
+	if got := accsum.SumGrid(id, .3, .1, n); math.Abs(got-want) > ulp(want) {
+		t.Fatalf("SumGrid = %.17g, want %.17g", got, want)
+	}
+	// Σ x^2 over the grid i/1024, i < 1024, is 1023*1024*2047/6 / 1024^2
+	sq := func(x float64) float64 { return x * x }
+	want = 1023. * 2047 / 6 / 1024
+	if got := accsum.SumGrid(sq, 0, 1./1024, 1024); math.Abs(got-want) > ulp(want) {
+		t.Fatalf("SumGrid of x^2 = %.17g, want %.17g", got, want)
+	}
+	if got := accsum.SumGrid(id, 1, 1, 0); got != 0 {
+		t.Fatalf("SumGrid with n = 0 = %g, want 0", got)
+	}
+}
+
+func TestFiniteDiffSum(t *testing.T) {
+	const h = 1e-3
+	y := make([]float64, 1001)
+	for i := range y {
+		y[i] = math.Sin(1 + float64(i)*h)
+	}
+	d := accsum.FiniteDiffSum(y, h)
+	for i, di := range d {
+		want := math.Cos(1 + float64(i)*h)
+		// truncation error h^2/6 for central differences, h/2 one-sided
+		tol := h * h / 6
+		if i == 0 || i == len(d)-1 {
+			tol = h / 2
+		}
+		if math.Abs(di-want) > tol+1e-12 {
+			t.Fatalf("d[%d] = %.17g, want %.17g", i, di, want)
+		}
+	}
+	// the quotient of the exact difference, rounded once
+	var q big.Float
+	for i := 1; i < len(y)-1; i++ {
+		q.SetPrec(200).Sub(big.NewFloat(y[i+1]), big.NewFloat(y[i-1]))
+		want, _ := q.Quo(&q, big.NewFloat(2*h)).Float64()
+		if math.Abs(d[i]-want) > ulp(want) {
+			t.Fatalf("d[%d] = %.17g, want %.17g", i, d[i], want)
+		}
+	}
+}
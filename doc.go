@@ -35,5 +35,13 @@
 // have to sum a bunch of numbers.  Sum2 is the first Rump algorithm; it is
 // fast and has has provable qualities.  AccSum gives great accuracy with
 // reasonable speed.  NearSum gives the true round-to-nearest result, although
-// at cost of time.
+// at cost of time.  Profile measures accuracy and speed of the summation
+// functions on your own data.
+//
+// All algorithms assume IEEE 754 arithmetic in round-to-nearest mode, which
+// is what Go specifies.  If the floating point unit has been put in some
+// other rounding mode, as is possible from C code called through cgo, the
+// error-free transformations are no longer error-free and results carry no
+// guarantees.  SumRNE does no floating point arithmetic and gives the
+// correctly rounded sum regardless of mode.
 package accsum
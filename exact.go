@@ -0,0 +1,319 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum
+
+// Exact.go:  Functions accepting or using arbitrary precision values of
+// the math/big package.
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+// SumMixed returns an accurate sum of the float64 values in p and the
+// arbitrary precision values in exact.
+//
+// Each element of exact is split into a sequence of float64s that sum to the
+// exact value of the element, so exact values participate in the sum
+// without first being rounded to float64.  Splitting stops only when a
+// remainder is too small to be represented as a float64.
+//
+// Result is a faithful rounding of the sum.  SumMixed is not destructive on
+// p or exact.
+func SumMixed(p []float64, exact []*big.Float) float64 {
+	q := append([]float64{}, p...)
+	for _, x := range exact {
+		q = appendSplit(q, x)
+	}
+	return AccSum(q)
+}
+
+// SumRats returns the sum of the rational values in p, correctly rounded to
+// the nearest float64.
+//
+// The values are summed exactly with big.Rat arithmetic and only the final
+// result is rounded, rather than rounding each value to float64 before
+// summing.
+func SumRats(p []*big.Rat) float64 {
+	var s big.Rat
+	for _, x := range p {
+		s.Add(&s, x)
+	}
+	f, _ := s.Float64()
+	return f
+}
+
+// appendSplit appends float64s summing to x, high order first.
+func appendSplit(q []float64, x *big.Float) []float64 {
+	r := new(big.Float).Copy(x)
+	var f big.Float
+	for r.Sign() != 0 {
+		h, _ := r.Float64()
+		if h == 0 {
+			break
+		}
+		q = append(q, h)
+		if math.IsInf(h, 0) {
+			break
+		}
+		r.Sub(r, f.SetFloat64(h))
+	}
+	return q
+}
+
+// SumStrings returns a sum of decimal values in s.
+//
+// Each string is parsed with strconv.ParseFloat, then parsed again with
+// math/big to a precision of 4 bits per character beyond that of a float64.
+// The difference is split into further float64s, so digits beyond float64
+// precision participate in the sum.  All values are summed with NearSum.
+// The result is thus the correctly rounded sum of the decimal values, except
+// when that sum is within the extended precision of a rounding boundary.
+// Hexadecimal values such as "0x1p-3" are accepted as with ParseFloat.
+//
+// Strings "Inf" and "NaN" in the forms accepted by ParseFloat are also
+// accepted.  If any value is Inf or NaN, the result is the same as that of
+// Sum on the parsed values.
+//
+// If a string does not parse or its value is out of float64 range, the
+// result is 0 with a *strconv.NumError, which includes the string.
+func SumStrings(s []string) (float64, error) {
+	q := make([]float64, 0, 2*len(s))
+	var b, d big.Float
+	finite := true
+	for _, str := range s {
+		x, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return 0, err
+		}
+		q = append(q, x)
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			finite = false
+			continue
+		}
+		b.SetPrec(uint(P + 4*len(str)))
+		if _, _, err := b.Parse(str, 0); err != nil {
+			return 0, &strconv.NumError{Func: "ParseFloat", Num: str, Err: err}
+		}
+		q = appendSplit(q, b.Sub(&b, d.SetFloat64(x)))
+	}
+	if !finite {
+		return Sum(q), nil
+	}
+	return NearSum(q), nil
+}
+
+// ExactSumSorted returns the sum of values in p, correctly rounded to the
+// nearest float64.
+//
+// A copy of p is sorted by decreasing magnitude and summed with math/big
+// arithmetic in enough precision that the sum is exact.  Only the final
+// result is rounded.  ExactSumSorted is slow but simple and so is useful as
+// a reference for testing other algorithms.
+//
+// If p contains an Inf or NaN, the result is the same as that of Sum.
+//
+// ExactSumSorted is not destructive on p.
+func ExactSumSorted(p []float64) float64 {
+	q := append(priest{}, p...)
+	for _, x := range q {
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return Sum(p)
+		}
+	}
+	sort.Sort(q)
+	var s, t big.Float
+	s.SetPrec(exactPrec(q))
+	for _, x := range q {
+		s.Add(&s, t.SetFloat64(x))
+	}
+	f, _ := s.Float64()
+	return f
+}
+
+// exactPrec returns a precision sufficient to represent the sum of values
+// in p exactly, where p is sorted by decreasing magnitude.
+func exactPrec(p []float64) uint {
+	if len(p) == 0 || p[0] == 0 {
+		return P
+	}
+	_, hi := math.Frexp(p[0])
+	lo := hi
+	for _, x := range p {
+		if x == 0 {
+			break
+		}
+		_, lo = math.Frexp(x)
+	}
+	return uint(hi-lo+P) + uint(bits.Len(uint(len(p))))
+}
+
+// SumRNE returns the sum of values in p, correctly rounded to the nearest
+// float64 with ties to even.
+//
+// The result does not depend on the rounding mode of the floating point
+// unit.  AccSum, NearSum, and the other algorithms of this package rely on
+// error-free transformations that are error-free only under round to nearest,
+// and so give no guarantees if the mode has been changed, for example by
+// C code called through cgo.  SumRNE does no floating point arithmetic at
+// all.  Each value is decoded from its bits with math.Float64bits and
+// accumulated exactly in a big.Int scaled by 2^1074.  Only the final
+// conversion to float64 rounds, done by math/big in software with mode
+// big.ToNearestEven.
+//
+// An exactly zero sum is -0 if all values in p are -0, +0 otherwise, as
+// IEEE 754 specifies for round to nearest.
+//
+// If p contains an Inf or NaN, the result is the same as that of Sum.
+//
+// SumRNE is slow, taking time proportional to the exponent range of the
+// values.  It is not destructive on p.
+func SumRNE(p []float64) float64 {
+	var s, t big.Int
+	negZero := true
+	for _, x := range p {
+		b := math.Float64bits(x)
+		e := uint(b >> 52 & 0x7ff)
+		m := b & (1<<52 - 1)
+		switch {
+		case e == 0x7ff:
+			return Sum(p)
+		case e == 0:
+			e = 1
+		default:
+			m |= 1 << 52
+		}
+		if b != 1<<63 {
+			negZero = false
+		}
+		t.Lsh(t.SetUint64(m), e-1)
+		if b>>63 != 0 {
+			s.Sub(&s, &t)
+		} else {
+			s.Add(&s, &t)
+		}
+	}
+	if s.Sign() == 0 {
+		if negZero && len(p) > 0 {
+			return math.Copysign(0, -1)
+		}
+		return 0
+	}
+	var f big.Float
+	f.SetMode(big.ToNearestEven)
+	f.SetInt(&s)
+	f.SetMantExp(&f, -1074)
+	r, _ := f.Float64()
+	return r
+}
+
+// SumRounded returns the sum of values in p rounded to the given number of
+// decimal places, with ties rounded to even.
+//
+// The sum is first computed in double-double precision as with AccSumK, so
+// rounding is of the accurate sum and not of a sum already rounded to
+// float64.  A sum just beyond a decimal tie, as with 1.125 + 1e-20 to two
+// places, thus rounds away from the tie.  The rounding itself is done with
+// math/big rational arithmetic and the result is the float64 nearest the
+// rounded decimal value.  Decimals can be negative, to round to tens,
+// hundreds, and so on.
+//
+// If p contains an Inf or NaN, the result is the same as that of Sum.
+// Values larger than 2^960 in magnitude are summed with ExactSumSorted.
+// SumRounded is not destructive on p.
+func SumRounded(p []float64, decimals int) float64 {
+	μ := 0.
+	for _, x := range p {
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return Sum(p)
+		}
+		μ = math.Max(μ, math.Abs(x))
+	}
+	var h, l float64
+	if μ <= 0x1p960 {
+		h, l = accSum2(append([]float64{}, p...))
+	} else {
+		h = ExactSumSorted(p)
+	}
+	var s, t big.Rat
+	s.SetFloat64(h)
+	s.Add(&s, t.SetFloat64(l))
+	d := int64(decimals)
+	if d < 0 {
+		d = -d
+	}
+	var ten big.Int
+	ten.Exp(big.NewInt(10), big.NewInt(d), nil)
+	t.SetInt(&ten)
+	if decimals < 0 {
+		t.Inv(&t)
+	}
+	s.Mul(&s, &t)
+	s.SetInt(roundEven(&s))
+	f, _ := s.Quo(&s, &t).Float64()
+	return f
+}
+
+// roundEven returns r rounded to the nearest integer, ties to even.
+func roundEven(r *big.Rat) *big.Int {
+	var m big.Int
+	q, _ := new(big.Int).QuoRem(r.Num(), r.Denom(), &m)
+	m.Lsh(m.Abs(&m), 1)
+	if c := m.Cmp(r.Denom()); c > 0 || c == 0 && q.Bit(0) == 1 {
+		q.Add(q, big.NewInt(int64(r.Sign())))
+	}
+	return q
+}
+
+// CheckTwoSum verifies the result of TwoSum(a, b).
+//
+// It returns true if TwoSum returns x equal to the floating point sum a+b
+// and x+y exactly equal to the sum of a and b, as verified with math/big
+// arithmetic.  It returns false otherwise, including when a, b, or the sum
+// is not finite.
+func CheckTwoSum(a, b float64) bool {
+	x, y := TwoSum(a, b)
+	if x != a+b || !finite(a, b, x, y) {
+		return false
+	}
+	return exactSum2(a, b).Cmp(exactSum2(x, y)) == 0
+}
+
+// CheckTwoProduct verifies the result of TwoProduct(a, b).
+//
+// It returns true if TwoProduct returns x equal to the floating point
+// product a*b and x+y exactly equal to the product of a and b, as verified
+// with math/big arithmetic.  It returns false otherwise, including when a,
+// b, or the result is not finite.
+//
+// Note TwoProduct is error-free only in the absence of underflow and
+// overflow, so CheckTwoProduct can return false for extreme values.
+func CheckTwoProduct(a, b float64) bool {
+	x, y := TwoProduct(a, b)
+	if x != a*b || !finite(a, b, x, y) {
+		return false
+	}
+	var ba, bb, p big.Float
+	p.SetPrec(2 * P)
+	p.Mul(ba.SetFloat64(a), bb.SetFloat64(b))
+	return p.Cmp(exactSum2(x, y)) == 0
+}
+
+func finite(x ...float64) bool {
+	for _, v := range x {
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// exactSum2 returns the exact sum of a and b.
+func exactSum2(a, b float64) *big.Float {
+	var ba, bb big.Float
+	return new(big.Float).SetPrec(2*EMax+2*P).Add(ba.SetFloat64(a), bb.SetFloat64(b))
+}
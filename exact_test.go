@@ -0,0 +1,198 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum_test
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/soniakeys/accsum"
+)
+
+func ExampleSumMixed() {
+	third := new(big.Float).SetPrec(200).Quo(big.NewFloat(1), big.NewFloat(3))
+	f, _ := third.Float64()
+	p := []float64{-f}
+	fmt.Printf("Pre-rounded: %.6e\n", accsum.AccSum([]float64{-f, f}))
+	fmt.Printf("SumMixed:    %.6e\n", accsum.SumMixed(p, []*big.Float{third}))
+	// Output:
+	// Pre-rounded: 0.000000e+00
+	// SumMixed:    1.850372e-17
+}
+
+func TestSumRats(t *testing.T) {
+	third := big.NewRat(1, 3)
+	r := []*big.Rat{third, third, third, big.NewRat(-1, 1)}
+	if s := accsum.SumRats(r); s != 0 {
+		t.Fatalf("SumRats = %g, want 0", s)
+	}
+	f, _ := third.Float64()
+	if s := accsum.NearSum([]float64{f, f, f, -1}); s == 0 {
+		t.Fatal("sum of pre-rounded thirds unexpectedly exact")
+	}
+	r = []*big.Rat{big.NewRat(1, 10), big.NewRat(2, 10), big.NewRat(-3, 10)}
+	if s := accsum.SumRats(r); s != 0 {
+		t.Fatalf("SumRats = %g, want 0", s)
+	}
+}
+
+func TestExactSumSorted(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		p := make([]float64, 1+rand.Intn(100))
+		for j := range p {
+			p[j] = math.Ldexp(rand.Float64()*2-1, rand.Intn(200)-100)
+		}
+		// add some cancellation
+		for j := 0; j < len(p)/2; j++ {
+			p = append(p, -p[j])
+		}
+		p = append(p, math.Ldexp(rand.Float64(), -120))
+		want := accsum.ExactSumSorted(p)
+		got := accsum.NearSum(append([]float64{}, p...))
+		if math.Float64bits(got) != math.Float64bits(want) {
+			t.Fatalf("p = %v\nNearSum:        %.17g\nExactSumSorted: %.17g",
+				p, got, want)
+		}
+	}
+}
+
+func TestSumRNE(t *testing.T) {
+	for _, tc := range []struct {
+		p    []float64
+		want float64
+	}{
+		{[]float64{1, 0x1p-53}, 1},                     // tie to even, down
+		{[]float64{1 + 0x1p-52, 0x1p-53}, 1 + 0x1p-51}, // tie to even, up
+		{[]float64{1, 0x1p-53, 0x1p-1074}, 1 + 0x1p-52},
+		{[]float64{math.MaxFloat64, math.MaxFloat64, -math.MaxFloat64}, math.MaxFloat64},
+		{[]float64{math.MaxFloat64, 0x1p970}, math.Inf(1)},
+		{[]float64{0x1p-1074, 0x1p-1074, -0x1p-1073}, 0},
+		{[]float64{math.Copysign(0, -1)}, math.Copysign(0, -1)},
+		{nil, 0},
+	} {
+		got := accsum.SumRNE(tc.p)
+		if math.Float64bits(got) != math.Float64bits(tc.want) {
+			t.Errorf("SumRNE(%g) = %.17g, want %.17g", tc.p, got, tc.want)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		p, _, _ := accsum.GenSum(100, 1e30)
+		want := accsum.ExactSumSorted(p)
+		if got := accsum.SumRNE(p); got != want {
+			t.Fatalf("SumRNE = %.17g, ExactSumSorted = %.17g", got, want)
+		}
+	}
+}
+
+func TestSumRounded(t *testing.T) {
+	for _, tc := range []struct {
+		p        []float64
+		decimals int
+		want     float64
+	}{
+		{[]float64{1.125}, 2, 1.12}, // exact tie, to even
+		{[]float64{1.375}, 2, 1.38},
+		{[]float64{1.125, 1e-20}, 2, 1.13}, // float64 sum is the tie
+		{[]float64{1.125, -1e-20}, 2, 1.12},
+		{[]float64{-1.125, -1e-20}, 2, -1.13},
+		{[]float64{1e20, 1.125, -1e20, 1e-20}, 2, 1.13},
+		{[]float64{2.5}, 0, 2},
+		{[]float64{.5, 1e-30}, 0, 1},
+		{[]float64{1250}, -2, 1200},
+		{[]float64{1250, 1e-10}, -2, 1300},
+		{[]float64{0x1p1000, 0x1p1000, -0x1p1000}, 3, 0x1p1000},
+		{nil, 2, 0},
+	} {
+		if got := accsum.SumRounded(tc.p, tc.decimals); got != tc.want {
+			t.Errorf("SumRounded(%g, %d) = %.17g, want %.17g",
+				tc.p, tc.decimals, got, tc.want)
+		}
+	}
+	if got := accsum.SumRounded([]float64{1, math.Inf(1)}, 2); !math.IsInf(got, 1) {
+		t.Errorf("SumRounded with +Inf = %g", got)
+	}
+}
+
+func TestSumStrings(t *testing.T) {
+	// 2^-53 plus a little, which rounds to 2^-53 as a float64
+	s := []string{"1", "1.1102230246251565404236316680908203125000001e-16"}
+	got, err := accsum.SumStrings(s)
+	if want := 1 + 0x1p-52; got != want || err != nil {
+		t.Fatalf("SumStrings = %.17g, %v, want %.17g, nil", got, err, want)
+	}
+	var p []float64
+	for _, str := range s {
+		x, _ := strconv.ParseFloat(str, 64)
+		p = append(p, x)
+	}
+	if naive := accsum.Sum2(p); naive != 1 {
+		t.Fatalf("Sum2 of parsed values = %.17g, expected 1", naive)
+	}
+	_, err = accsum.SumStrings([]string{"1", "1.5x"})
+	if _, ok := err.(*strconv.NumError); !ok || !strings.Contains(err.Error(), "1.5x") {
+		t.Fatalf("SumStrings error = %v, want error with \"1.5x\"", err)
+	}
+}
+
+func TestSumStringsSpecial(t *testing.T) {
+	for _, tc := range []struct {
+		s    []string
+		want float64
+	}{
+		{[]string{"1", "Inf"}, math.Inf(1)},
+		{[]string{"-infinity", "2"}, math.Inf(-1)},
+		{[]string{"0x1p-3", "0.125"}, .25},
+		{[]string{"0x1.8p1", "-1"}, 2},
+	} {
+		got, err := accsum.SumStrings(tc.s)
+		if got != tc.want || err != nil {
+			t.Errorf("SumStrings(%q) = %g, %v, want %g, nil", tc.s, got, err, tc.want)
+		}
+	}
+	if got, err := accsum.SumStrings([]string{"NaN", "1"}); !math.IsNaN(got) || err != nil {
+		t.Errorf("SumStrings with NaN = %g, %v, want NaN, nil", got, err)
+	}
+}
+
+func FuzzTwoSum(f *testing.F) {
+	f.Add(.1, .2)
+	f.Add(1e20, -1.)
+	f.Add(math.MaxFloat64, -math.SmallestNonzeroFloat64)
+	f.Fuzz(func(t *testing.T, a, b float64) {
+		if s := a + b; math.IsInf(s, 0) || math.IsNaN(s) {
+			t.Skip()
+		}
+		if !accsum.CheckTwoSum(a, b) {
+			x, y := accsum.TwoSum(a, b)
+			t.Fatalf("TwoSum(%g, %g) = %g, %g", a, b, x, y)
+		}
+	})
+}
+
+func FuzzTwoProduct(f *testing.F) {
+	f.Add(.1, .2)
+	f.Add(1e10+1, 1e6+1)
+	f.Add(-3e150, 7e-140)
+	f.Fuzz(func(t *testing.T, a, b float64) {
+		// TwoProduct is error-free only without overflow in split and
+		// without underflow of the error term.
+		const lim = 0x1p995
+		if !(math.Abs(a) < lim && math.Abs(b) < lim) {
+			t.Skip()
+		}
+		if p := math.Abs(a * b); math.IsInf(p, 0) ||
+			p != 0 && p < 0x1p-968 {
+			t.Skip()
+		}
+		if !accsum.CheckTwoProduct(a, b) {
+			x, y := accsum.TwoProduct(a, b)
+			t.Fatalf("TwoProduct(%g, %g) = %g, %g", a, b, x, y)
+		}
+	})
+}
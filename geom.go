@@ -0,0 +1,154 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum
+
+// Geom.go:  Operations on small fixed-size vectors, as used in geometry and
+// graphics.
+
+import (
+	"fmt"
+	"math"
+)
+
+// Dot4 returns the dot product of 4-vectors x and y, as if computed in
+// twice the precision of a float64.
+//
+// The result is the same as that of Dot2 on slices of length 4.  Dot4 is
+// fully unrolled and does not allocate.
+func Dot4(x0, x1, x2, x3, y0, y1, y2, y3 float64) float64 {
+	p, s := TwoProduct(x0, y0)
+	h, r := TwoProduct(x1, y1)
+	p, q := TwoSum(p, h)
+	s += q + r
+	h, r = TwoProduct(x2, y2)
+	p, q = TwoSum(p, h)
+	s += q + r
+	h, r = TwoProduct(x3, y3)
+	p, q = TwoSum(p, h)
+	s += q + r
+	return p + s
+}
+
+// Cross3 returns the cross product of 3-vectors a and b.
+//
+// Each component, a difference of two products, is computed from the
+// products transformed error-free with TwoProduct and summed with AccSum.
+// Each component is thus a faithful rounding of the exact value and in
+// particular has the correct sign, even for nearly parallel vectors.
+// Underflow is assumed not to occur.
+func Cross3(ax, ay, az, bx, by, bz float64) (cx, cy, cz float64) {
+	return diffProd(ay, bz, az, by),
+		diffProd(az, bx, ax, bz),
+		diffProd(ax, by, ay, bx)
+}
+
+// diffProd returns a faithful rounding of a*b - c*d.
+func diffProd(a, b, c, d float64) float64 {
+	var p [4]float64
+	p[0], p[1] = TwoProduct(a, b)
+	p[2], p[3] = TwoProduct(-c, d)
+	return AccSum(p[:])
+}
+
+// Triple returns the scalar triple product a · (b × c) of 3-vectors a, b,
+// and c.
+//
+// The triple product is transformed error-free with TwoProduct into 24
+// terms and summed with AccSum.  The result is thus a faithful rounding of
+// the exact value and in particular has the correct sign, and is zero only
+// when the exact value is zero.  Underflow is assumed not to occur.
+func Triple(ax, ay, az, bx, by, bz, cx, cy, cz float64) float64 {
+	p := make([]float64, 0, 24)
+	for _, t := range [...][3]float64{
+		{ax, by, cz}, {-ax, bz, cy},
+		{ay, bz, cx}, {-ay, bx, cz},
+		{az, bx, cy}, {-az, by, cx},
+	} {
+		h, r := TwoProduct(t[1], t[2])
+		h1, h2 := TwoProduct(t[0], h)
+		r1, r2 := TwoProduct(t[0], r)
+		p = append(p, h1, h2, r1, r2)
+	}
+	return AccSum(p)
+}
+
+// Orient2D returns the orientation of points a, b, and c in the plane.
+//
+// The result is +1 if a, b, c are in counterclockwise order, -1 if
+// clockwise, and 0 if collinear.  That is, it is the sign of the
+// determinant (b-a) × (c-a).
+//
+// Coordinates are first scaled by a power of two, which does not change the
+// sign, so that the largest is near 2^450 and no product can overflow.  The
+// coordinate differences are then transformed error-free with TwoSum and the
+// determinant expanded error-free with TwoProduct into 16 terms, so the sign
+// is exact.  This holds for all finite coordinates whose nonzero magnitudes
+// are within a factor of 2^900 of the largest, beyond which scaling or
+// products can underflow.  Orient2D panics if any coordinate is Inf or NaN.
+func Orient2D(ax, ay, bx, by, cx, cy float64) int {
+	c := [...]float64{ax, ay, bx, by, cx, cy}
+	m := 0.
+	for _, x := range c {
+		m = math.Max(m, math.Abs(x))
+	}
+	if math.IsInf(m, 0) || math.IsNaN(m) {
+		panic(fmt.Sprintf("Orient2D(%g, %g, %g, %g, %g, %g): "+
+			"coordinate not finite", ax, ay, bx, by, cx, cy))
+	}
+	if m == 0 {
+		return 0
+	}
+	k := 449 - math.Ilogb(m)
+	for i, x := range c {
+		c[i] = math.Ldexp(x, k)
+	}
+	ax, ay, bx, by, cx, cy = c[0], c[1], c[2], c[3], c[4], c[5]
+	d := det2(bx, -ax, cy, -ay, by, -ay, cx, -ax)
+	switch {
+	case d > 0:
+		return 1
+	case d < 0:
+		return -1
+	}
+	return 0
+}
+
+// det2 returns a faithful rounding of (a1+a2)*(b1+b2) - (c1+c2)*(d1+d2).
+func det2(a1, a2, b1, b2, c1, c2, d1, d2 float64) float64 {
+	p := make([]float64, 0, 16)
+	p = appendProd(p, a1, a2, b1, b2)
+	return AccSum(appendProd(p, -c1, -c2, d1, d2))
+}
+
+// appendProd appends to p terms summing exactly to (a1+a2)*(b1+b2).
+func appendProd(p []float64, a1, a2, b1, b2 float64) []float64 {
+	a, ae := TwoSum(a1, a2)
+	b, be := TwoSum(b1, b2)
+	for _, f := range [...][2]float64{{a, b}, {a, be}, {ae, b}, {ae, be}} {
+		h, r := TwoProduct(f[0], f[1])
+		p = append(p, h, r)
+	}
+	return p
+}
+
+// Solve2x2 solves the linear system
+//
+//	a11*x1 + a12*x2 = b1
+//	a21*x1 + a22*x2 = b2
+//
+// by Cramer's rule.
+//
+// The determinant and both numerators are differences of two products,
+// computed as faithful roundings as with Cross3.  Ok is false if and only if
+// the exact determinant is zero, in which case x1 and x2 are 0.  Otherwise
+// each of x1 and x2 is a quotient of faithfully rounded values, with
+// relative error less than 3 units in the last place, however nearly
+// singular the system.  Underflow and overflow are assumed not to occur.
+func Solve2x2(a11, a12, a21, a22, b1, b2 float64) (x1, x2 float64, ok bool) {
+	d := diffProd(a11, a22, a12, a21)
+	if d == 0 {
+		return 0, 0, false
+	}
+	return diffProd(b1, a22, a12, b2) / d, diffProd(a11, b2, b1, a21) / d, true
+}
@@ -0,0 +1,225 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum_test
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/soniakeys/accsum"
+)
+
+func TestDot4(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		x := randSlice(4)
+		y := randSlice(4)
+		x[3] = -x[0] * y[0] / y[3] // some cancellation
+		got := accsum.Dot4(x[0], x[1], x[2], x[3], y[0], y[1], y[2], y[3])
+		if want := accsum.Dot2(x, y); got != want {
+			t.Fatalf("Dot4(%v, %v) = %.17g, want %.17g", x, y, got, want)
+		}
+	}
+}
+
+var dot4Sink float64
+
+func BenchmarkDot4(b *testing.B) {
+	x := randSlice(4)
+	y := randSlice(4)
+	for i := 0; i < b.N; i++ {
+		dot4Sink = accsum.Dot4(x[0], x[1], x[2], x[3], y[0], y[1], y[2], y[3])
+	}
+}
+
+func BenchmarkDot2Len4(b *testing.B) {
+	x := randSlice(4)
+	y := randSlice(4)
+	for i := 0; i < b.N; i++ {
+		dot4Sink = accsum.Dot2(x, y)
+	}
+}
+
+// bigTriple returns the exact value of a · (b × c).
+func bigTriple(a, b, c [3]float64) *big.Float {
+	r := new(big.Float).SetPrec(1000)
+	var t big.Float
+	t.SetPrec(1000)
+	term := func(s float64, x, y, z float64) {
+		t.SetFloat64(s * x)
+		t.Mul(&t, big.NewFloat(y))
+		t.Mul(&t, big.NewFloat(z))
+		r.Add(r, &t)
+	}
+	term(1, a[0], b[1], c[2])
+	term(-1, a[0], b[2], c[1])
+	term(1, a[1], b[2], c[0])
+	term(-1, a[1], b[0], c[2])
+	term(1, a[2], b[0], c[1])
+	term(-1, a[2], b[1], c[0])
+	return r
+}
+
+// nearlyParallel returns a random vector and one nearly parallel to it.
+func nearlyParallel() (a, b [3]float64) {
+	k := 1 + rand.Float64()
+	for i := range a {
+		a[i] = rand.Float64()*2 - 1
+		b[i] = a[i] * k
+	}
+	b[rand.Intn(3)] *= 1 + (rand.Float64()-.5)*1e-15
+	return
+}
+
+func TestCross3(t *testing.T) {
+	naiveWrong := 0
+	for i := 0; i < 1000; i++ {
+		a, b := nearlyParallel()
+		cx, cy, cz := accsum.Cross3(a[0], a[1], a[2], b[0], b[1], b[2])
+		c := [3]float64{cx, cy, cz}
+		for j := 0; j < 3; j++ {
+			// component j of a × b is the triple product e_j · (a × b)
+			var e [3]float64
+			e[j] = 1
+			want, _ := bigTriple(e, a, b).Float64()
+			if math.Abs(c[j]-want) > ulp(want) {
+				t.Fatalf("Cross3(%v, %v)[%d] = %g, want %g",
+					a, b, j, c[j], want)
+			}
+			k, l := (j+1)%3, (j+2)%3
+			naive := a[k]*b[l] - a[l]*b[k]
+			if math.Signbit(naive) != math.Signbit(want) || naive == 0 && want != 0 {
+				naiveWrong++
+			}
+		}
+	}
+	if naiveWrong == 0 {
+		t.Fatal("naive cross product always correct, test ineffective")
+	}
+}
+
+func TestTriple(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		a, b := nearlyParallel()
+		c := [3]float64{rand.Float64(), rand.Float64(), rand.Float64()}
+		got := accsum.Triple(a[0], a[1], a[2], b[0], b[1], b[2], c[0], c[1], c[2])
+		exact := bigTriple(a, b, c)
+		want, _ := exact.Float64()
+		if math.Abs(got-want) > ulp(want) || (got == 0) != (exact.Sign() == 0) {
+			t.Fatalf("Triple(%v, %v, %v) = %g, want %g", a, b, c, got, want)
+		}
+	}
+}
+
+func TestOrient2D(t *testing.T) {
+	bigOrient := func(ax, ay, bx, by, cx, cy float64) int {
+		f := func(x float64) *big.Float { return new(big.Float).SetPrec(500).SetFloat64(x) }
+		d1 := f(bx)
+		d1.Sub(d1, f(ax))
+		d2 := f(cy)
+		d2.Sub(d2, f(ay))
+		d3 := f(by)
+		d3.Sub(d3, f(ay))
+		d4 := f(cx)
+		d4.Sub(d4, f(ax))
+		d1.Mul(d1, d2)
+		d3.Mul(d3, d4)
+		return d1.Cmp(d3)
+	}
+	// exactly collinear
+	if o := accsum.Orient2D(0, 0, 1, 1, 3, 3); o != 0 {
+		t.Fatalf("Orient2D collinear = %d, want 0", o)
+	}
+	if o := accsum.Orient2D(.5, .5, 12, 12, 24, 24); o != 0 {
+		t.Fatalf("Orient2D collinear = %d, want 0", o)
+	}
+	if o := accsum.Orient2D(0, 0, 1, 0, 0, 1); o != 1 {
+		t.Fatalf("Orient2D counterclockwise = %d, want 1", o)
+	}
+	// nearly collinear, the classic test grid near the line y = x
+	naiveWrong := 0
+	for i := 0; i < 256; i++ {
+		for j := 0; j < 256; j++ {
+			ax := .5 + float64(i)*0x1p-53
+			ay := .5 + float64(j)*0x1p-53
+			bx, by, cx, cy := 12., 12., 24., 24.
+			want := bigOrient(ax, ay, bx, by, cx, cy)
+			if got := accsum.Orient2D(ax, ay, bx, by, cx, cy); got != want {
+				t.Fatalf("Orient2D(%g, %g, ...) = %d, want %d", ax, ay, got, want)
+			}
+			naive := (bx-ax)*(cy-ay) - (by-ay)*(cx-ax)
+			ns := 0
+			switch {
+			case naive > 0:
+				ns = 1
+			case naive < 0:
+				ns = -1
+			}
+			if ns != want {
+				naiveWrong++
+			}
+		}
+	}
+	if naiveWrong == 0 {
+		t.Fatal("naive orientation always correct, test ineffective")
+	}
+	// products of large coordinates overflow, of small ones underflow
+	if o := accsum.Orient2D(0, 0, 1e300, 1, 2e300, 3); o != 1 {
+		t.Fatalf("Orient2D with large coordinates = %d, want 1", o)
+	}
+	for _, scale := range []int{-1000, -600, 600, 1000} {
+		for i := 0; i < 100; i++ {
+			ax := math.Ldexp(.5+float64(rand.Intn(256))*0x1p-53, scale)
+			ay := math.Ldexp(.5+float64(rand.Intn(256))*0x1p-53, scale)
+			bx, by := math.Ldexp(12, scale), math.Ldexp(12, scale)
+			cx, cy := math.Ldexp(24, scale), math.Ldexp(24, scale)
+			want := bigOrient(ax, ay, bx, by, cx, cy)
+			if got := accsum.Orient2D(ax, ay, bx, by, cx, cy); got != want {
+				t.Fatalf("Orient2D(%g, %g, ...) = %d, want %d", ax, ay, got, want)
+			}
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Orient2D with NaN coordinate did not panic")
+		}
+	}()
+	accsum.Orient2D(0, 0, 1, math.NaN(), 2, 3)
+}
+
+func TestSolve2x2(t *testing.T) {
+	f := func(x float64) *big.Float { return new(big.Float).SetPrec(500).SetFloat64(x) }
+	diff := func(a, b, c, d float64) *big.Float {
+		p := f(a)
+		p.Mul(p, f(b))
+		q := f(c)
+		q.Mul(q, f(d))
+		return p.Sub(p, q)
+	}
+	for i := 0; i < 1000; i++ {
+		// nearly singular: rows nearly parallel
+		a, b := nearlyParallel()
+		a11, a12, a21, a22 := a[0], a[1], b[0], b[1]
+		b1, b2 := rand.Float64(), rand.Float64()
+		x1, x2, ok := accsum.Solve2x2(a11, a12, a21, a22, b1, b2)
+		d := diff(a11, a22, a12, a21)
+		if !ok {
+			if d.Sign() != 0 {
+				t.Fatalf("Solve2x2 not ok for nonsingular system")
+			}
+			continue
+		}
+		n1 := diff(b1, a22, a12, b2)
+		n2 := diff(a11, b2, b1, a21)
+		w1, _ := n1.Quo(n1, d).Float64()
+		w2, _ := n2.Quo(n2, d).Float64()
+		if math.Abs(x1-w1) > 3*ulp(w1) || math.Abs(x2-w2) > 3*ulp(w2) {
+			t.Fatalf("Solve2x2 = %g, %g, want %g, %g", x1, x2, w1, w2)
+		}
+	}
+	if _, _, ok := accsum.Solve2x2(1, 2, 2, 4, 1, 1); ok {
+		t.Fatal("Solve2x2 ok for singular system")
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum
+
+// Mat.go:  Operations on matrices represented as slices of rows.
+
+import "fmt"
+
+// MatMul2 returns the matrix product a·b.
+//
+// Each element of the result is the dot product of a row of a and a column
+// of b, as computed by Dot2.
+//
+// A and b must be rectangular with the number of columns of a equal to the
+// number of rows of b.  MatMul2 panics otherwise.
+func MatMul2(a, b [][]float64) [][]float64 {
+	ac := cols(a, "a")
+	bc := cols(b, "b")
+	if ac != len(b) {
+		panic(fmt.Sprintf("a has %d columns, b has %d rows", ac, len(b)))
+	}
+	col := make([]float64, len(b))
+	c := make([][]float64, len(a))
+	for i := range c {
+		c[i] = make([]float64, bc)
+	}
+	for j := 0; j < bc; j++ {
+		for k, row := range b {
+			col[k] = row[j]
+		}
+		for i, row := range a {
+			c[i][j] = Dot2(row, col)
+		}
+	}
+	return c
+}
+
+// cols returns the number of columns of m, panicking if m is ragged.
+// Name is used in the panic message.
+func cols(m [][]float64, name string) int {
+	if len(m) == 0 {
+		return 0
+	}
+	n := len(m[0])
+	for i, row := range m {
+		if len(row) != n {
+			panic(fmt.Sprintf("len(%s[%d]) = %d, want len(%s[0]) = %d",
+				name, i, len(row), name, n))
+		}
+	}
+	return n
+}
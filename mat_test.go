@@ -0,0 +1,63 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum_test
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/soniakeys/accsum"
+)
+
+// bigDot returns the dot product of x and y correctly rounded.
+func bigDot(x, y []float64) float64 {
+	s := new(big.Float).SetPrec(2200)
+	var p, bx, by big.Float
+	p.SetPrec(2 * accsum.P)
+	for i, xi := range x {
+		s.Add(s, p.Mul(bx.SetFloat64(xi), by.SetFloat64(y[i])))
+	}
+	f, _ := s.Float64()
+	return f
+}
+
+func TestMatMul2(t *testing.T) {
+	x, y, _, _ := accsum.GenDot(20, 1e20)
+	// a is 3x20, b is 20x2.  a[0]·b[:,0] is ill-conditioned.
+	a := [][]float64{x, randSlice(20), randSlice(20)}
+	b := make([][]float64, 20)
+	for k := range b {
+		b[k] = []float64{y[k], randSlice(1)[0]}
+	}
+	c := accsum.MatMul2(a, b)
+	if len(c) != 3 || len(c[0]) != 2 {
+		t.Fatalf("MatMul2 result %dx%d, want 3x2", len(c), len(c[0]))
+	}
+	for i := range c {
+		for j := range c[i] {
+			col := make([]float64, len(b))
+			abs := make([]float64, len(b))
+			for k, row := range b {
+				col[k] = row[j]
+				abs[k] = math.Abs(row[j] * a[i][k])
+			}
+			if d := accsum.Dot2(a[i], col); c[i][j] != d {
+				t.Fatalf("c[%d][%d] = %g, Dot2 %g", i, j, c[i][j], d)
+			}
+			want := bigDot(a[i], col)
+			// a priori bound for Dot2
+			eb := ulp(want) + 1e-30*accsum.Sum(abs)
+			if math.Abs(c[i][j]-want) > eb {
+				t.Fatalf("c[%d][%d] = %.17g, want %.17g", i, j, c[i][j], want)
+			}
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MatMul2 did not panic on nonconformable matrices")
+		}
+	}()
+	accsum.MatMul2(a, a)
+}
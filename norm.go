@@ -0,0 +1,68 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum
+
+// Norm.go:  Sums of squares and norms.
+
+import "math"
+
+// SumSquares returns the sum of squares of values in p, as if computed in
+// twice the precision of a float64.
+//
+// Values are scaled by a power of two so that intermediate squares neither
+// overflow nor lose accuracy to underflow.  The result overflows only if
+// the sum itself exceeds the float64 range.  Each square is formed
+// error-free with TwoProduct and the squares and their errors summed as in
+// Dot2.  If any value is infinite, the result is +Inf, otherwise if any
+// value is NaN, the result is NaN.
+func SumSquares(p []float64) float64 {
+	s, e := sumSquares([][]float64{p})
+	return math.Ldexp(s, 2*e)
+}
+
+// FrobNorm returns the Frobenius norm of m, the square root of the sum of
+// squares of all elements.
+//
+// The sum of squares is computed as with SumSquares.  The square root is
+// taken before scaling back, so the result overflows or underflows only if
+// the norm itself is out of float64 range.  Rows may differ in length.
+func FrobNorm(m [][]float64) float64 {
+	s, e := sumSquares(m)
+	return math.Ldexp(math.Sqrt(s), e)
+}
+
+// sumSquares returns s, the sum of squares of elements of m scaled by
+// 2^(-2e).
+func sumSquares(m [][]float64) (s float64, e int) {
+	μ := 0.
+	nan := false
+	for _, row := range m {
+		for _, x := range row {
+			if a := math.Abs(x); a > μ {
+				μ = a
+			} else if x != x {
+				nan = true
+			}
+		}
+	}
+	switch {
+	case math.IsInf(μ, 0):
+		return μ, 0
+	case nan:
+		return math.NaN(), 0
+	case μ == 0:
+		return 0, 0
+	}
+	_, e = math.Frexp(μ)
+	var c, q float64
+	for _, row := range m {
+		for _, x := range row {
+			x = math.Ldexp(x, -e)
+			h, r := TwoProduct(x, x)
+			s, q = TwoSum(s, h)
+			c += q + r
+		}
+	}
+	return s + c, e
+}
@@ -0,0 +1,89 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum_test
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/soniakeys/accsum"
+)
+
+// bigSumSquares returns the sum of squares of p computed with big.Float.
+func bigSumSquares(p []float64) float64 {
+	s := new(big.Float).SetPrec(4400)
+	var x, sq big.Float
+	sq.SetPrec(4400)
+	for _, f := range p {
+		x.SetFloat64(f)
+		s.Add(s, sq.Mul(&x, &x))
+	}
+	r, _ := s.Float64()
+	return r
+}
+
+func TestSumSquares(t *testing.T) {
+	p := []float64{1.2e154, -5e153, 3, 1e140}
+	want := bigSumSquares(p)
+	if math.IsInf(want, 0) {
+		t.Fatal("test case overflows")
+	}
+	got := accsum.SumSquares(p)
+	if math.Abs(got-want) > ulp(want) {
+		t.Fatalf("SumSquares = %.17g, want %.17g", got, want)
+	}
+	// squares that lose accuracy to underflow
+	p = []float64{1.5e-155, 1.5e-160, -7e-171}
+	want = bigSumSquares(p)
+	if got = accsum.SumSquares(p); got != want {
+		t.Fatalf("SumSquares = %.17g, want %.17g", got, want)
+	}
+	if got = accsum.SumSquares([]float64{1e200, 1e200}); !math.IsInf(got, 1) {
+		t.Fatalf("SumSquares = %g, want +Inf", got)
+	}
+}
+
+func TestFrobNorm(t *testing.T) {
+	for _, m := range [][][]float64{
+		{{1e200, 1e-200, 3}, {3e200, -4e-300}},
+		{{1e-200, 2e-200}, {-3e-200, 1e-300}},
+		{{3, 4}},
+	} {
+		s := new(big.Float).SetPrec(4400)
+		var x, sq big.Float
+		sq.SetPrec(4400)
+		for _, row := range m {
+			for _, f := range row {
+				x.SetFloat64(f)
+				s.Add(s, sq.Mul(&x, &x))
+			}
+		}
+		want, _ := s.Sqrt(s).Float64()
+		got := accsum.FrobNorm(m)
+		if math.Abs(got-want) > ulp(want) {
+			t.Fatalf("FrobNorm(%v) = %.17g, want %.17g", m, got, want)
+		}
+	}
+}
+
+func TestSumSquaresSpecial(t *testing.T) {
+	nan := math.NaN()
+	inf := math.Inf(-1)
+	for _, tc := range []struct {
+		p    []float64
+		want float64
+	}{
+		{nil, 0},
+		{[]float64{0, 0}, 0},
+		{[]float64{nan}, nan},
+		{[]float64{1, nan}, nan},
+		{[]float64{nan, inf}, math.Inf(1)},
+	} {
+		got := accsum.SumSquares(tc.p)
+		if got != tc.want && !(math.IsNaN(got) && math.IsNaN(tc.want)) {
+			t.Fatalf("SumSquares(%v) = %g, want %g", tc.p, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum
+
+// Poly.go:  Polynomial evaluation.
+//
+// Coefficients are given in order of increasing degree, so coeffs[i] is the
+// coefficient of x^i.
+//
+// Algorithms of "Algorithms for Accurate, Validated and Fast Polynomial
+// Evaluation," S. Graillat, Ph. Langlois, and N. Louvet, Japan J. Indust.
+// Appl. Math. 26 (2009).
+//
+// CompHorner (3)
+// CompHornerErr (4)
+//
+// CompDeriv is a straightforward extension of CompHorner, compensating the
+// Horner recurrence for the derivative as well.
+
+import (
+	"fmt"
+	"math"
+)
+
+// CompHorner evaluates the polynomial with coefficients coeffs at x, as if
+// computed in twice the precision of a float64.
+//
+// This is the compensated Horner scheme.  The rounding errors of each
+// Horner step are computed error-free with TwoProduct and TwoSum and are
+// themselves evaluated as a polynomial to correct the result.
+func CompHorner(coeffs []float64, x float64) float64 {
+	y, c, _ := compHorner(coeffs, x)
+	return y + c
+}
+
+// CompHornerErr returns the same result as CompHorner together with a
+// rigorous error bound.
+//
+// The bound is a posteriori, computed from the error terms of the evaluation
+// so it is typically much smaller than the a priori bound
+// eps*|p(x)| + γ(2n)^2 * Σ|coeffs[i]|*|x|^i.  Underflow is assumed not to
+// occur.
+func CompHornerErr(coeffs []float64, x float64) (y, eb float64) {
+	y, c, b := compHorner(coeffs, x)
+	y += c
+	n := len(coeffs) - 1
+	if n < 1 {
+		return
+	}
+	m := float64(2*n - 1)
+	γ := m * eps / (1 - m*eps)
+	// 1 - 2(n+1)eps covers rounding in the evaluation of b itself.
+	α := (γ*b + 2*eps*eps*math.Abs(y)) / (1 - 2*float64(n+1)*eps)
+	eb = (eps*math.Abs(y) + α) / (1 - 2*eps)
+	return
+}
+
+// CompDeriv evaluates the polynomial with coefficients coeffs and its
+// derivative at x, both as if computed in twice the precision of a float64.
+//
+// The value and derivative are computed together with the Horner recurrences
+// d = d*x + h, h = h*x + coeffs[i].  The rounding errors of both are
+// computed error-free with TwoProduct and TwoSum and the correction for h
+// is carried into the correction for d.  The result val is the same as that
+// of CompHorner.  Accurate values near a multiple root, where the
+// derivative is also small, allow Newton iteration to converge to full
+// precision there.
+func CompDeriv(coeffs []float64, x float64) (val, deriv float64) {
+	if len(coeffs) == 0 {
+		return
+	}
+	n := len(coeffs) - 1
+	h := coeffs[n]
+	var d, ch, cd float64
+	for i := n - 1; i >= 0; i-- {
+		p, π := TwoProduct(d, x)
+		var σ float64
+		d, σ = TwoSum(p, h)
+		cd = cd*x + ch + (π + σ)
+		p, π = TwoProduct(h, x)
+		h, σ = TwoSum(p, coeffs[i])
+		ch = ch*x + (π + σ)
+	}
+	return h + ch, d + cd
+}
+
+// SeriesEval returns the partial sum of the power series with coefficients
+// coeffs at x, Σ coeffs[k]*x^k for k = 0 to n-1.
+//
+// The partial sum is a polynomial of degree n-1 and is evaluated with
+// CompHorner, as if computed in twice the precision of a float64.  This
+// matters for x near or beyond the radius of convergence, or for series
+// such as that of exp(-x) at large x, where terms are large and
+// alternating and the sum is much smaller than the terms.  The result then
+// has relative error of about eps + cond*eps^2, where cond is the ratio of
+// Σ |coeffs[k]*x^k| to the magnitude of the sum, compared to about
+// cond*eps for plain Horner evaluation.
+//
+// SeriesEval panics if n is negative or greater than len(coeffs).
+func SeriesEval(coeffs []float64, x float64, n int) float64 {
+	if n < 0 || n > len(coeffs) {
+		panic(fmt.Sprintf("n = %d, want 0 <= n <= len(coeffs) = %d",
+			n, len(coeffs)))
+	}
+	return CompHorner(coeffs[:n], x)
+}
+
+// compHorner returns the Horner result h, the compensating correction c,
+// and b, the polynomial of absolute error terms evaluated at |x|.
+func compHorner(coeffs []float64, x float64) (h, c, b float64) {
+	if len(coeffs) == 0 {
+		return
+	}
+	n := len(coeffs) - 1
+	h = coeffs[n]
+	ax := math.Abs(x)
+	for i := n - 1; i >= 0; i-- {
+		p, π := TwoProduct(h, x)
+		var σ float64
+		h, σ = TwoSum(p, coeffs[i])
+		c = c*x + (π + σ)
+		b = b*ax + (math.Abs(π) + math.Abs(σ))
+	}
+	return
+}
+
+// NevilleEval evaluates at xi the polynomial interpolating points (x[i], y[i])
+// with Neville's algorithm, as if computed in twice the precision of a
+// float64.
+//
+// Each intermediate interpolant is carried as a double-length value.
+// Differences of abscissas are formed error-free with TwoSum, products with
+// TwoProduct, and quotients are computed to double length.  Results thus
+// stay accurate near data points where the updates suffer cancellation.
+//
+// Abscissas x must be distinct.  NevilleEval panics if x and y differ in
+// length or are empty.  X and y are not modified.
+func NevilleEval(x, y []float64, xi float64) float64 {
+	if len(y) != len(x) {
+		panic(fmt.Sprintf("len(y) = %d, want len(x) = %d", len(y), len(x)))
+	}
+	if len(x) == 0 {
+		panic("len(x) = 0, need at least 1 point")
+	}
+	ph := append([]float64{}, y...)
+	pl := make([]float64, len(y))
+	for m := 1; m < len(x); m++ {
+		for i := 0; i+m < len(x); i++ {
+			ah, al := TwoSum(xi, -x[i+m])
+			bh, bl := TwoSum(x[i], -xi)
+			dh, dl := TwoSum(x[i], -x[i+m])
+			s1, e1 := TwoProduct(ah, ph[i])
+			s2, e2 := TwoProduct(bh, ph[i+1])
+			nh, e3 := TwoSum(s1, s2)
+			nl := e1 + e2 + e3 + ah*pl[i] + al*ph[i] + bh*pl[i+1] + bl*ph[i+1]
+			ph[i], pl[i] = ddQuo(nh, nl, dh, dl)
+		}
+	}
+	return ph[0] + pl[0]
+}
+
+// BaryEval evaluates at xi the polynomial interpolating points (x[i], y[i])
+// with the barycentric formula
+//
+//	Σ w[i]*y[i]/(xi-x[i]) / Σ w[i]/(xi-x[i])
+//
+// as if computed in twice the precision of a float64.
+//
+// W holds the barycentric weights of the abscissas x.  Weights need only be
+// correct to a common factor, so for equispaced points, for example, w[i]
+// can be (-1)^i times the binomial coefficient C(n, i).  Differences
+// xi-x[i] are formed error-free with TwoSum and the quotients computed to
+// double length, so results stay accurate for xi near a data point where
+// the terms of both sums are large and nearly cancel.  If xi equals some
+// x[j] the result is y[j].
+//
+// Abscissas x must be distinct.  BaryEval panics if w or y differ in length
+// from x or if x is empty.  The slices are not modified.
+func BaryEval(x, w, y []float64, xi float64) float64 {
+	if len(w) != len(x) {
+		panic(fmt.Sprintf("len(w) = %d, want len(x) = %d", len(w), len(x)))
+	}
+	if len(y) != len(x) {
+		panic(fmt.Sprintf("len(y) = %d, want len(x) = %d", len(y), len(x)))
+	}
+	if len(x) == 0 {
+		panic("len(x) = 0, need at least 1 point")
+	}
+	var ns, ne, ds, de, q float64
+	for i, xj := range x {
+		if xi == xj {
+			return y[i]
+		}
+		dh, dl := TwoSum(xi, -xj)
+		th, tl := ddQuo(w[i], 0, dh, dl)
+		ds, q = TwoSum(ds, th)
+		de += q + tl
+		h, r := TwoProduct(th, y[i])
+		ns, q = TwoSum(ns, h)
+		ne += q + (r + tl*y[i])
+	}
+	ns, ne = TwoSum(ns, ne)
+	ds, de = TwoSum(ds, de)
+	return ddDiv(ns, ne, ds, de)
+}
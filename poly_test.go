@@ -0,0 +1,175 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum_test
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/soniakeys/accsum"
+)
+
+// bigHorner evaluates a polynomial exactly, returning a big.Float.
+func bigHorner(coeffs []float64, x float64) *big.Float {
+	h := new(big.Float).SetPrec(4000)
+	var bx, c big.Float
+	bx.SetFloat64(x)
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		h.Mul(h, &bx)
+		h.Add(h, c.SetFloat64(coeffs[i]))
+	}
+	return h
+}
+
+// binomialPoly returns coefficients of (x-r)^n.
+func binomialPoly(r float64, n int) []float64 {
+	c := []float64{1}
+	for k := 0; k < n; k++ {
+		d := make([]float64, len(c)+1)
+		for i, ci := range c {
+			d[i+1] += ci
+			d[i] -= r * ci
+		}
+		c = d
+	}
+	return c
+}
+
+// chebyshev returns coefficients of the Chebyshev polynomial T_n.
+func chebyshev(n int) []float64 {
+	t0, t1 := []float64{1}, []float64{0, 1}
+	for k := 1; k < n; k++ {
+		t2 := make([]float64, k+2)
+		for i, c := range t1 {
+			t2[i+1] += 2 * c
+		}
+		for i, c := range t0 {
+			t2[i] -= c
+		}
+		t0, t1 = t1, t2
+	}
+	return t1
+}
+
+func TestCompHornerErr(t *testing.T) {
+	for _, tc := range []struct {
+		coeffs []float64
+		x      []float64
+	}{
+		{binomialPoly(.75, 7), []float64{.74, .7499, .75, .7500001, .76}},
+		{binomialPoly(2, 11), []float64{1.99, 1.999999, 2.0001}},
+		{chebyshev(20), []float64{.9, math.Cos(math.Pi / 40), .12345}},
+	} {
+		for _, x := range tc.x {
+			y, eb := accsum.CompHornerErr(tc.coeffs, x)
+			if y != accsum.CompHorner(tc.coeffs, x) {
+				t.Fatal("CompHornerErr and CompHorner differ")
+			}
+			want := bigHorner(tc.coeffs, x)
+			var lo, hi big.Float
+			lo.SetPrec(200)
+			hi.SetPrec(200)
+			lo.Sub(big.NewFloat(y), big.NewFloat(eb))
+			hi.Add(big.NewFloat(y), big.NewFloat(eb))
+			if want.Cmp(&lo) < 0 || want.Cmp(&hi) > 0 {
+				t.Fatalf("p(%g) = %g, not within %g ± %g",
+					x, want, y, eb)
+			}
+		}
+	}
+}
+
+func TestCompDeriv(t *testing.T) {
+	// (x-.75)^5 has a root of multiplicity 5 at .75.
+	c := binomialPoly(.75, 5)
+	dc := make([]float64, len(c)-1)
+	for i := range dc {
+		dc[i] = float64(i+1) * c[i+1]
+	}
+	for _, dx := range []float64{1e-2, -1e-3, 1e-3, 3e-4} {
+		x := .75 + dx
+		val, deriv := accsum.CompDeriv(c, x)
+		if v := accsum.CompHorner(c, x); val != v {
+			t.Fatalf("x = %g, val = %.17g, CompHorner = %.17g", x, val, v)
+		}
+		wv, _ := bigHorner(c, x).Float64()
+		wd, _ := bigHorner(dc, x).Float64()
+		if math.Abs(val-wv) > 1e-10*math.Abs(wv) {
+			t.Errorf("x = %g, val = %.17g, want %.17g", x, val, wv)
+		}
+		if math.Abs(deriv-wd) > 1e-10*math.Abs(wd) {
+			t.Errorf("x = %g, deriv = %.17g, want %.17g", x, deriv, wd)
+		}
+	}
+	if v, d := accsum.CompDeriv(nil, 2); v != 0 || d != 0 {
+		t.Fatalf("CompDeriv(nil) = %g, %g, want 0, 0", v, d)
+	}
+	if v, d := accsum.CompDeriv([]float64{3}, 2); v != 3 || d != 0 {
+		t.Fatalf("CompDeriv(3) = %g, %g, want 3, 0", v, d)
+	}
+}
+
+func TestSeriesEval(t *testing.T) {
+	// series of exp(x), evaluated at x = -20 where terms reach about 4e7
+	// and alternate, for a sum of about 2e-9
+	c := make([]float64, 100)
+	f := 1.
+	for k := range c {
+		c[k] = 1 / f
+		f *= float64(k + 1)
+	}
+	for _, n := range []int{10, 50, 100} {
+		want, _ := bigHorner(c[:n], -20).Float64()
+		got := accsum.SeriesEval(c, -20, n)
+		if math.Abs(got-want) > 1e-13*math.Abs(want) {
+			t.Errorf("SeriesEval(n = %d) = %.17g, want %.17g", n, got, want)
+		}
+	}
+	if got := accsum.SeriesEval(c, -20, 0); got != 0 {
+		t.Errorf("SeriesEval(n = 0) = %g, want 0", got)
+	}
+}
+
+func TestNevilleEval(t *testing.T) {
+	c := chebyshev(8)
+	x := make([]float64, len(c))
+	y := make([]float64, len(c))
+	for i := range x {
+		x[i] = float64(i)
+		y[i], _ = bigHorner(c, x[i]).Float64()
+	}
+	for _, xi := range []float64{.5, 3.5, 3 + 1e-9, 4 - 1e-12, 7.25, 8 + 1e-6} {
+		want, _ := bigHorner(c, xi).Float64()
+		if got := accsum.NevilleEval(x, y, xi); math.Abs(got-want) > 4*ulp(want) {
+			t.Errorf("NevilleEval(%g) = %.17g, want %.17g", xi, got, want)
+		}
+	}
+}
+
+func TestBaryEval(t *testing.T) {
+	c := chebyshev(8)
+	n := len(c) - 1
+	x := make([]float64, n+1)
+	y := make([]float64, n+1)
+	w := make([]float64, n+1)
+	b := 1.
+	for i := range x {
+		x[i] = float64(i)
+		y[i], _ = bigHorner(c, x[i]).Float64()
+		w[i] = b
+		b = -b * float64(n-i) / float64(i+1)
+	}
+	for _, xi := range []float64{.5, 3.5, 3 + 1e-9, 4 - 1e-12, 7.25, 8 + 1e-6} {
+		want, _ := bigHorner(c, xi).Float64()
+		if got := accsum.BaryEval(x, w, y, xi); math.Abs(got-want) > 4*ulp(want) {
+			t.Errorf("BaryEval(%g) = %.17g, want %.17g", xi, got, want)
+		}
+	}
+	for i, xi := range x {
+		if got := accsum.BaryEval(x, w, y, xi); got != y[i] {
+			t.Errorf("BaryEval(%g) = %.17g, want %.17g", xi, got, y[i])
+		}
+	}
+}
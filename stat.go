@@ -0,0 +1,623 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum
+
+// Stat.go:  Statistical functions built on accurate summation.
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// WeightedMean returns the mean of values in x weighted by corresponding
+// values in w.
+//
+// The weighted sum of x and the sum of w are accumulated together in a
+// single pass, each as if computed in twice the precision of a float64.
+// The two sums are divided only at the end.
+//
+// W and x must be of the same length, panic or nonsense results otherwise.
+// If the sum of weights is zero, the result is NaN.
+func WeightedMean(w, x []float64) float64 {
+	var s, e, sw, ew, q float64
+	for i, wi := range w {
+		h, r := TwoProduct(wi, x[i])
+		s, q = TwoSum(s, h)
+		e += q + r
+		sw, q = TwoSum(sw, wi)
+		ew += q
+	}
+	dh, dl := TwoSum(sw, ew)
+	if dh == 0 {
+		return math.NaN()
+	}
+	nh, nl := TwoSum(s, e)
+	return ddDiv(nh, nl, dh, dl)
+}
+
+// MixAccurate returns the convex combination Σ w[i]*x[i] / Σ w[i].
+//
+// It differs from WeightedMean in accuracy.  Products are split error-free
+// with TwoProduct and both the numerator and the sum of weights are computed
+// in 2-fold precision as with AccSumK, then divided once.  The result is
+// thus nearly always the correctly rounded quotient, even when weights
+// nearly cancel so that the sum of weights is ill-conditioned.
+//
+// If any value or product is Inf or NaN, or larger than 2^960 in magnitude,
+// the result is that of WeightedMean.  If the sum of weights is zero, the
+// result is NaN.  MixAccurate panics if w and x differ in length.
+func MixAccurate(w, x []float64) float64 {
+	if len(x) != len(w) {
+		panic(fmt.Sprintf("len(x) = %d, want len(w) = %d", len(x), len(w)))
+	}
+	num := make([]float64, 0, 2*len(w))
+	for i, wi := range w {
+		h, r := TwoProduct(wi, x[i])
+		if !(math.Abs(h) <= 0x1p960 && math.Abs(wi) <= 0x1p960) {
+			return WeightedMean(w, x)
+		}
+		num = append(num, h, r)
+	}
+	dh, dl := accSum2(append([]float64{}, w...))
+	if dh == 0 {
+		return math.NaN()
+	}
+	nh, nl := accSum2(num)
+	return ddDiv(nh, nl, dh, dl)
+}
+
+// ddDiv returns the quotient of double-length values nh+nl and dh+dl,
+// rounded once to float64.
+func ddDiv(nh, nl, dh, dl float64) float64 {
+	q, c := ddQuo(nh, nl, dh, dl)
+	return q + c
+}
+
+// ddQuo returns the quotient of double-length values nh+nl and dh+dl as a
+// double-length value q+c.
+func ddQuo(nh, nl, dh, dl float64) (q, c float64) {
+	q = nh / dh
+	p, r := TwoProduct(q, dh)
+	return FastTwoSum(q, (nh-p-r+nl-q*dl)/dh)
+}
+
+// Reduce returns the sum, minimum, and maximum of values in p, computed in a
+// single pass.
+//
+// The sum is computed as with Sum2.  Min and max are computed as with
+// math.Min and math.Max, so a NaN anywhere in p gives NaN for all three
+// results.  For empty p, sum is 0, min is +Inf, and max is -Inf.
+func Reduce(p []float64) (sum, min, max float64) {
+	min = math.Inf(1)
+	max = math.Inf(-1)
+	var e, y float64
+	for _, x := range p {
+		sum, y = TwoSum(sum, x)
+		e += y
+		min = math.Min(min, x)
+		max = math.Max(max, x)
+	}
+	sum += e
+	return
+}
+
+// Summary holds descriptive statistics of a slice as returned by Summarize.
+type Summary struct {
+	Sum, Mean, Min, Max, SumAbs float64
+	Count                       int
+}
+
+// Summarize returns a Summary of values in p, computed in a single pass.
+//
+// Sum and SumAbs are computed as with Sum2, Mean as with Mean, and Min and
+// Max as with Reduce.  For empty p, Mean is NaN, Min is +Inf, and Max is
+// -Inf.
+func Summarize(p []float64) Summary {
+	r := Summary{Min: math.Inf(1), Max: math.Inf(-1), Count: len(p)}
+	var e, ea, y float64
+	for _, x := range p {
+		r.Sum, y = TwoSum(r.Sum, x)
+		e += y
+		r.SumAbs, y = TwoSum(r.SumAbs, math.Abs(x))
+		ea += y
+		r.Min = math.Min(r.Min, x)
+		r.Max = math.Max(r.Max, x)
+	}
+	r.Sum, e = TwoSum(r.Sum, e)
+	if len(p) == 0 {
+		r.Mean = math.NaN()
+	} else {
+		r.Mean = ddDiv(r.Sum, e, float64(len(p)), 0)
+	}
+	r.SumAbs += ea
+	return r
+}
+
+// SSR returns the sum of squared residuals Σ (observed[i]-predicted[i])^2,
+// as if computed in twice the precision of a float64.
+//
+// Each residual is formed error-free with TwoSum and squared with
+// TwoProduct, the low order part contributing to the compensation term.
+// Accuracy is thus retained for a very good fit, where observed and
+// predicted values nearly cancel.
+//
+// Observed and predicted must be of the same length, panic or nonsense
+// results otherwise.
+func SSR(observed, predicted []float64) float64 {
+	var s, e, q float64
+	for i, o := range observed {
+		rh, rl := TwoSum(o, -predicted[i])
+		h, r := TwoProduct(rh, rh)
+		s, q = TwoSum(s, h)
+		e += q + (r + (2*rh+rl)*rl)
+	}
+	return s + e
+}
+
+// Mean returns the arithmetic mean of values in p.
+//
+// The sum is computed as with Sum2 and divided by len(p) before rounding.
+// For empty p, the result is NaN.
+func Mean(p []float64) float64 {
+	if len(p) == 0 {
+		return math.NaN()
+	}
+	var s, e, y float64
+	for _, x := range p {
+		s, y = TwoSum(s, x)
+		e += y
+	}
+	s, e = TwoSum(s, e)
+	return ddDiv(s, e, float64(len(p)), 0)
+}
+
+// NanSum returns a sum of the values in p that are not NaN.
+//
+// NaN values are skipped, as for missing data.  The sum of remaining values
+// is computed as with Sum2.  If p is empty or all NaN, the result is 0.
+func NanSum(p []float64) float64 {
+	s, e, _ := nanSum(p)
+	return s + e
+}
+
+// NanMean returns the mean of the values in p that are not NaN.
+//
+// NaN values are skipped and the sum of remaining values, computed as with
+// Sum2, is divided by their count as in Mean.  If p is empty or all NaN,
+// the result is NaN.
+func NanMean(p []float64) float64 {
+	s, e, n := nanSum(p)
+	if n == 0 {
+		return math.NaN()
+	}
+	s, e = TwoSum(s, e)
+	return ddDiv(s, e, float64(n), 0)
+}
+
+// nanSum returns the double-length sum s+e and count n of non-NaN values in
+// p.
+func nanSum(p []float64) (s, e float64, n int) {
+	var y float64
+	for _, x := range p {
+		if x == x {
+			s, y = TwoSum(s, x)
+			e += y
+			n++
+		}
+	}
+	return
+}
+
+// Normalize returns a new slice with the values of p divided by their sum.
+//
+// The sum is computed as with Sum2 and kept as a double-length value, and
+// each quotient is rounded only once.  Each element of the result is thus
+// nearly correctly rounded, and for non-negative p the result sums to 1
+// within a few units of eps = 2^-53.  If the sum is zero, every element of
+// the result is NaN.  Normalize is not destructive on p.
+func Normalize(p []float64) []float64 {
+	var s, e, y float64
+	for _, x := range p {
+		s, y = TwoSum(s, x)
+		e += y
+	}
+	s, e = TwoSum(s, e)
+	q := make([]float64, len(p))
+	for i, x := range p {
+		if s == 0 {
+			q[i] = math.NaN()
+		} else {
+			q[i] = ddDiv(x, 0, s, e)
+		}
+	}
+	return q
+}
+
+// GeoMean returns the geometric mean of values in p.
+//
+// The result is computed as exp(Mean(log(p))) and so does not overflow or
+// underflow for any representable values.  Accuracy is limited by that of
+// math.Log and math.Exp.
+//
+// All values of p must be positive.  If any is zero, negative, or NaN, or
+// if p is empty, the result is NaN.
+func GeoMean(p []float64) float64 {
+	l := make([]float64, len(p))
+	for i, x := range p {
+		if !(x > 0) {
+			return math.NaN()
+		}
+		l[i] = math.Log(x)
+	}
+	return math.Exp(Mean(l))
+}
+
+// TrimmedSum returns the sum of values in p after discarding the lowest
+// lowFrac and highest highFrac fractions of values.
+//
+// A copy of p is sorted and floor(lowFrac*len(p)) values are dropped from
+// the low end and floor(highFrac*len(p)) from the high end.  The remaining
+// values are summed with Sum2.  TrimmedSum is not destructive on p.
+//
+// TrimmedSum panics unless lowFrac and highFrac are non-negative with a sum
+// less than 1.
+func TrimmedSum(p []float64, lowFrac, highFrac float64) float64 {
+	if !(lowFrac >= 0 && highFrac >= 0 && lowFrac+highFrac < 1) {
+		panic(fmt.Sprintf("invalid trim fractions %g, %g", lowFrac, highFrac))
+	}
+	q := append([]float64{}, p...)
+	sort.Float64s(q)
+	lo := int(lowFrac * float64(len(q)))
+	hi := len(q) - int(highFrac*float64(len(q)))
+	return Sum2(q[lo:hi])
+}
+
+// CovAccumulator accumulates the covariance and correlation of paired
+// observations.
+//
+// Means and co-moments are updated with Welford's method, each carried as a
+// double-length value.  Deviations from the means are formed error-free with
+// TwoSum and products with TwoProduct, so results stay accurate even when
+// means are large relative to the spread of the data.
+//
+// The zero value is an empty accumulator ready to use.
+type CovAccumulator struct {
+	n             float64
+	mx, my        dd // means
+	cxy, cxx, cyy dd // co-moments
+}
+
+// Add adds the observation pair x, y.
+func (c *CovAccumulator) Add(x, y float64) {
+	c.n++
+	dx := c.mx.welford(x, c.n)
+	dy := c.my.welford(y, c.n)
+	rx := c.mx.from(x)
+	ry := c.my.from(y)
+	c.cxy.addProd(dx, ry)
+	c.cxx.addProd(dx, rx)
+	c.cyy.addProd(dy, ry)
+}
+
+// Cov returns the sample covariance of pairs added so far.
+//
+// The co-moment is divided by n-1.  For fewer than two pairs the result is
+// NaN.
+func (c *CovAccumulator) Cov() float64 {
+	if c.n < 2 {
+		return math.NaN()
+	}
+	return ddDiv(c.cxy.h, c.cxy.l, c.n-1, 0)
+}
+
+// Corr returns the Pearson correlation coefficient of pairs added so far.
+//
+// For fewer than two pairs, or if either x or y values are all equal, the
+// result is NaN.
+func (c *CovAccumulator) Corr() float64 {
+	sx := math.Sqrt(c.cxx.h + c.cxx.l)
+	sy := math.Sqrt(c.cyy.h + c.cyy.l)
+	if c.n < 2 || sx == 0 || sy == 0 {
+		return math.NaN()
+	}
+	return (c.cxy.h + c.cxy.l) / sx / sy
+}
+
+// MomentsAccumulator accumulates the variance, skewness, and kurtosis of a
+// stream of values.
+//
+// The mean and the central moment sums M2, M3, and M4 are updated with the
+// one-value case of the formulas of Pébay, "Formulas for Robust, One-Pass
+// Parallel Computation of Covariances and Arbitrary-Order Statistical
+// Moments," Sandia Report SAND2008-6212.  All are carried as double-length
+// values, with products formed by TwoProduct and sums by TwoSum.
+//
+// The zero value is an empty accumulator ready to use.
+type MomentsAccumulator struct {
+	n          float64
+	mean       dd
+	m2, m3, m4 dd
+}
+
+// Add adds x to the values.
+func (m *MomentsAccumulator) Add(x float64) {
+	n1 := m.n
+	m.n++
+	n := m.n
+	δ := m.mean.welford(x, n)
+	var δn dd
+	δn.h, δn.l = ddQuo(δ.h, δ.l, n, 0)
+	δn2 := δn.mul(δn)
+	t := δ.mul(δn).scale(n1)
+	m.m4 = m.m4.add(t.mul(δn2).scale(n*n - 3*n + 3)).
+		add(δn2.mul(m.m2).scale(6)).
+		add(δn.mul(m.m3).scale(-4))
+	m.m3 = m.m3.add(t.mul(δn).scale(n - 2)).add(δn.mul(m.m2).scale(-3))
+	m.m2 = m.m2.add(t)
+}
+
+// Variance returns the sample variance, M2/(n-1).  For fewer than two
+// values the result is NaN.
+func (m *MomentsAccumulator) Variance() float64 {
+	if m.n < 2 {
+		return math.NaN()
+	}
+	return ddDiv(m.m2.h, m.m2.l, m.n-1, 0)
+}
+
+// Skewness returns the population skewness, sqrt(n)*M3/M2^(3/2).  If all
+// values are equal or there are fewer than two, the result is NaN.
+func (m *MomentsAccumulator) Skewness() float64 {
+	m2 := m.m2.h + m.m2.l
+	if m.n < 2 || m2 == 0 {
+		return math.NaN()
+	}
+	return math.Sqrt(m.n) * (m.m3.h + m.m3.l) / (m2 * math.Sqrt(m2))
+}
+
+// Kurtosis returns the population excess kurtosis, n*M4/M2^2 - 3.  If all
+// values are equal or there are fewer than two, the result is NaN.
+func (m *MomentsAccumulator) Kurtosis() float64 {
+	if m.n < 2 || m.m2.h == 0 {
+		return math.NaN()
+	}
+	q := m.m4.scale(m.n)
+	q.h, q.l = ddQuo(q.h, q.l, m.m2.h, m.m2.l)
+	q.h, q.l = ddQuo(q.h, q.l, m.m2.h, m.m2.l)
+	return q.h - 3 + q.l
+}
+
+// StreamStats accumulates the sum, mean, and variance of a stream of values
+// together with approximate quantiles.
+//
+// The sum is accumulated as with Accumulator, and the mean and the sum of
+// squared deviations M2 are updated with Welford's method as double-length
+// values, as in MomentsAccumulator.  Quantiles are estimated with the
+// extended P² algorithm of Raatikainen, "Simultaneous estimation of several
+// percentiles," Simulation 49 (1987), an extension of Jain and Chlamtac's
+// P² algorithm.  It keeps streamMarkers marker heights, estimating the
+// minimum, the maximum, and quantiles at steps of 1/(streamMarkers-1)
+// between, and adjusts them with piecewise parabolic interpolation as values
+// arrive.  Memory use is constant.  Until streamMarkers values have been
+// added, quantiles are exact.
+//
+// The zero value is an empty StreamStats ready to use.
+type StreamStats struct {
+	sum  Accumulator
+	n    float64
+	mean dd
+	m2   dd
+	h    [streamMarkers]float64 // marker heights
+	pos  [streamMarkers]float64 // marker positions, 1 based
+}
+
+// streamMarkers is the number of quantile markers of StreamStats.
+const streamMarkers = 21
+
+// Add adds x to the values.
+func (s *StreamStats) Add(x float64) {
+	s.sum.Add(x)
+	s.n++
+	d := s.mean.welford(x, s.n)
+	s.m2.addProd(d, s.mean.from(x))
+	if s.n <= streamMarkers {
+		// collect the first values in sorted order
+		i := int(s.n) - 1
+		for ; i > 0 && s.h[i-1] > x; i-- {
+			s.h[i] = s.h[i-1]
+		}
+		s.h[i] = x
+		s.pos[int(s.n)-1] = s.n
+		return
+	}
+	const m = streamMarkers
+	var k int
+	switch {
+	case x < s.h[0]:
+		s.h[0] = x
+	case x >= s.h[m-1]:
+		s.h[m-1] = x
+		k = m - 2
+	default:
+		for k = 0; x >= s.h[k+1]; k++ {
+		}
+	}
+	for i := k + 1; i < m; i++ {
+		s.pos[i]++
+	}
+	for i := 1; i < m-1; i++ {
+		d := 1 + (s.n-1)*float64(i)/(m-1) - s.pos[i]
+		if d >= 1 && s.pos[i+1]-s.pos[i] > 1 ||
+			d <= -1 && s.pos[i-1]-s.pos[i] < -1 {
+			s.adjust(i, math.Copysign(1, d))
+		}
+	}
+}
+
+// adjust moves marker i by one position in direction d, ±1.
+func (s *StreamStats) adjust(i int, d float64) {
+	h, n := &s.h, &s.pos
+	p := h[i] + d/(n[i+1]-n[i-1])*
+		((n[i]-n[i-1]+d)*(h[i+1]-h[i])/(n[i+1]-n[i])+
+			(n[i+1]-n[i]-d)*(h[i]-h[i-1])/(n[i]-n[i-1]))
+	if !(h[i-1] < p && p < h[i+1]) {
+		j := i + int(d)
+		p = h[i] + d*(h[j]-h[i])/(n[j]-n[i])
+	}
+	h[i] = p
+	n[i] += d
+}
+
+// Count returns the number of values added.
+func (s *StreamStats) Count() int64 {
+	return s.sum.Count()
+}
+
+// Sum returns the sum of values added, as with Accumulator.Sum.
+func (s *StreamStats) Sum() float64 {
+	return s.sum.Sum()
+}
+
+// Mean returns the mean of values added.  For no values the result is NaN.
+func (s *StreamStats) Mean() float64 {
+	if s.n == 0 {
+		return math.NaN()
+	}
+	return s.mean.h + s.mean.l
+}
+
+// Variance returns the sample variance, M2/(n-1).  For fewer than two
+// values the result is NaN.
+func (s *StreamStats) Variance() float64 {
+	if s.n < 2 {
+		return math.NaN()
+	}
+	return ddDiv(s.m2.h, s.m2.l, s.n-1, 0)
+}
+
+// StdDev returns the sample standard deviation, the square root of
+// Variance.
+func (s *StreamStats) StdDev() float64 {
+	return math.Sqrt(s.Variance())
+}
+
+// Quantile returns an estimate of the q-quantile of values added, by linear
+// interpolation between markers.  Quantile(0) is the minimum and
+// Quantile(1) the maximum, both exact.  Estimates for q between the two
+// outermost markers at either end, as for q = .99, are correspondingly
+// rough.  For no values the result is NaN.
+// Quantile panics if q is not in [0, 1].
+func (s *StreamStats) Quantile(q float64) float64 {
+	if !(q >= 0 && q <= 1) {
+		panic(fmt.Sprintf("q = %g, want 0 <= q <= 1", q))
+	}
+	m := math.Min(s.n, streamMarkers)
+	if m == 0 {
+		return math.NaN()
+	}
+	f, r := math.Modf(q * (m - 1))
+	i := int(f)
+	if r == 0 {
+		return s.h[i]
+	}
+	return s.h[i] + r*(s.h[i+1]-s.h[i])
+}
+
+// dd is a double-length value h+l.
+type dd struct{ h, l float64 }
+
+// from returns v-m as a double-length value.
+func (m dd) from(v float64) dd {
+	h, l := TwoSum(v, -m.h)
+	h, l = TwoSum(h, l-m.l)
+	return dd{h, l}
+}
+
+// welford updates mean m with v, the n-th value, and returns the deviation
+// of v from the previous mean.
+func (m *dd) welford(v, n float64) dd {
+	d := m.from(v)
+	q, r := ddQuo(d.h, d.l, n, 0)
+	h, t := TwoSum(m.h, q)
+	m.h, m.l = TwoSum(h, m.l+t+r)
+	return d
+}
+
+// addProd adds the product a*b to m.
+func (m *dd) addProd(a, b dd) {
+	p, e := TwoProduct(a.h, b.h)
+	h, t := TwoSum(m.h, p)
+	m.h, m.l = TwoSum(h, m.l+t+e+a.h*b.l+a.l*b.h)
+}
+
+// add returns a+b.
+func (a dd) add(b dd) dd {
+	h, t := TwoSum(a.h, b.h)
+	h, t = FastTwoSum(h, t+a.l+b.l)
+	return dd{h, t}
+}
+
+// mul returns a*b.
+func (a dd) mul(b dd) dd {
+	p, e := TwoProduct(a.h, b.h)
+	p, e = FastTwoSum(p, e+a.h*b.l+a.l*b.h)
+	return dd{p, e}
+}
+
+// scale returns a*f.
+func (a dd) scale(f float64) dd {
+	p, e := TwoProduct(a.h, f)
+	p, e = FastTwoSum(p, e+a.l*f)
+	return dd{p, e}
+}
+
+// ExpBuckets holds values grouped by binary exponent, for investigating
+// which magnitudes contribute to a sum.
+type ExpBuckets struct {
+	p   []float64 // values ordered by exponent
+	exp []int     // exponents of p
+}
+
+// NewExpBuckets returns an ExpBuckets holding a copy of the values in p.
+//
+// Exponents are those returned by math.Ilogb, so a value x with exponent e
+// has 2^e <= |x| < 2^(e+1).  Zero has exponent math.MinInt32, and Inf and
+// NaN have exponent math.MaxInt32.
+func NewExpBuckets(p []float64) *ExpBuckets {
+	b := &ExpBuckets{
+		p:   append([]float64{}, p...),
+		exp: make([]int, len(p)),
+	}
+	sort.Sort(byExp(b.p))
+	for i, x := range b.p {
+		b.exp[i] = math.Ilogb(x)
+	}
+	return b
+}
+
+type byExp []float64
+
+func (p byExp) Len() int           { return len(p) }
+func (p byExp) Less(i, j int) bool { return math.Ilogb(p[i]) < math.Ilogb(p[j]) }
+func (p byExp) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// SumRange returns an accurate sum of values with exponents in the
+// inclusive range loExp to hiExp.
+//
+// The result is a faithful rounding of the sum, computed with AccSum.  If
+// the values in range include an Inf or NaN, the result is the same as that
+// of Sum.
+func (b *ExpBuckets) SumRange(loExp, hiExp int) float64 {
+	lo := sort.SearchInts(b.exp, loExp)
+	hi := sort.Search(len(b.exp), func(i int) bool { return b.exp[i] > hiExp })
+	if hi <= lo {
+		return 0
+	}
+	q := append([]float64{}, b.p[lo:hi]...)
+	if !finite(q...) {
+		return Sum(q)
+	}
+	return AccSum(q)
+}
@@ -0,0 +1,446 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum_test
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/soniakeys/accsum"
+)
+
+// ulp returns the spacing of float64s at x.
+func ulp(x float64) float64 {
+	x = math.Abs(x)
+	return math.Nextafter(x, math.Inf(1)) - x
+}
+
+func TestWeightedMean(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		n := 1 + rand.Intn(1000)
+		w := make([]float64, n)
+		x := make([]float64, n)
+		num := new(big.Float).SetPrec(2200)
+		den := new(big.Float).SetPrec(2200)
+		var tm, tw, tx big.Float
+		tm.SetPrec(106)
+		for j := range w {
+			w[j] = rand.Float64() * math.Ldexp(1, rand.Intn(40))
+			x[j] = (rand.Float64()*2 - 1) * math.Ldexp(1, rand.Intn(40))
+			tw.SetFloat64(w[j])
+			tm.Mul(&tw, tx.SetFloat64(x[j]))
+			num.Add(num, &tm)
+			den.Add(den, &tw)
+		}
+		want, _ := num.Quo(num, den).Float64()
+		got := accsum.WeightedMean(w, x)
+		if d := math.Abs(got - want); d > ulp(want) {
+			t.Fatalf("WeightedMean = %.17g, want %.17g", got, want)
+		}
+		sep := accsum.Dot2(w, x) / accsum.Sum2(w)
+		if d := math.Abs(got - sep); d > 2*ulp(sep) {
+			t.Fatalf("WeightedMean = %.17g, Dot2/Sum2 = %.17g", got, sep)
+		}
+	}
+	if m := accsum.WeightedMean([]float64{1, -1}, []float64{3, 4}); !math.IsNaN(m) {
+		t.Fatalf("WeightedMean with zero total weight = %g, want NaN", m)
+	}
+}
+
+func TestSSR(t *testing.T) {
+	obs := make([]float64, 1000)
+	pred := make([]float64, len(obs))
+	want := new(big.Float).SetPrec(500)
+	var bo, bp big.Float
+	for i := range obs {
+		obs[i] = 1e6 * (1 + rand.Float64())
+		pred[i] = obs[i] * (1 + (rand.Float64()-.5)*1e-13) // near-perfect fit
+		bo.SetPrec(500).SetFloat64(obs[i])
+		bo.Sub(&bo, bp.SetFloat64(pred[i]))
+		want.Add(want, bo.Mul(&bo, &bo))
+	}
+	w, _ := want.Float64()
+	if got := accsum.SSR(obs, pred); math.Abs(got-w) > ulp(w) {
+		t.Fatalf("SSR = %.17g, want %.17g", got, w)
+	}
+}
+
+func TestMixAccurate(t *testing.T) {
+	worse := 0
+	for i := 0; i < 100; i++ {
+		// weights nearly cancel, sum of weights has condition number 1e20
+		w, _, _ := accsum.GenSum(100, 1e20)
+		x := make([]float64, len(w))
+		num := new(big.Float).SetPrec(2200)
+		den := new(big.Float).SetPrec(2200)
+		var tm, tw, tx big.Float
+		tm.SetPrec(106)
+		for j := range x {
+			x[j] = rand.Float64()
+			tw.SetFloat64(w[j])
+			tm.Mul(&tw, tx.SetFloat64(x[j]))
+			num.Add(num, &tm)
+			den.Add(den, &tw)
+		}
+		want, _ := new(big.Float).Quo(num, den).Float64()
+		if got := accsum.MixAccurate(w, x); got != want {
+			t.Fatalf("MixAccurate = %.17g, want %.17g", got, want)
+		}
+		if accsum.WeightedMean(w, x) != want {
+			worse++
+		}
+	}
+	if worse == 0 {
+		t.Fatal("WeightedMean unexpectedly accurate on ill-conditioned weights")
+	}
+	if m := accsum.MixAccurate([]float64{1, -1}, []float64{3, 4}); !math.IsNaN(m) {
+		t.Fatalf("MixAccurate with zero total weight = %g, want NaN", m)
+	}
+}
+
+func TestStreamStats(t *testing.T) {
+	var s accsum.StreamStats
+	if !math.IsNaN(s.Mean()) || !math.IsNaN(s.Quantile(.5)) {
+		t.Fatal("empty StreamStats: want NaN mean and quantile")
+	}
+	for _, x := range []float64{3, 1, 2} {
+		s.Add(x)
+	}
+	if s.Quantile(0) != 1 || s.Quantile(.5) != 2 || s.Quantile(.75) != 2.5 ||
+		s.Quantile(1) != 3 {
+		t.Fatalf("quantiles of 3, 1, 2 = %g, %g, %g, %g",
+			s.Quantile(0), s.Quantile(.5), s.Quantile(.75), s.Quantile(1))
+	}
+
+	// large mean relative to the spread
+	s = accsum.StreamStats{}
+	p := make([]float64, 100000)
+	for i := range p {
+		p[i] = 1e6 + rand.NormFloat64()
+		s.Add(p[i])
+	}
+	var m, v, d big.Float
+	m.SetPrec(2200)
+	v.SetPrec(2200)
+	for _, x := range p {
+		m.Add(&m, d.SetFloat64(x))
+	}
+	m.Quo(&m, d.SetFloat64(float64(len(p))))
+	for _, x := range p {
+		d.SetPrec(2200).SetFloat64(x)
+		d.Sub(&d, &m)
+		v.Add(&v, d.Mul(&d, &d))
+	}
+	v.Quo(&v, d.SetFloat64(float64(len(p)-1)))
+	wm, _ := m.Float64()
+	wv, _ := v.Float64()
+	if got := s.Mean(); math.Abs(got-wm) > ulp(wm) {
+		t.Fatalf("Mean = %.17g, want %.17g", got, wm)
+	}
+	if got := s.Variance(); math.Abs(got-wv) > 4*ulp(wv) {
+		t.Fatalf("Variance = %.17g, want %.17g", got, wv)
+	}
+	if got, want := s.StdDev(), math.Sqrt(wv); math.Abs(got-want) > 4*ulp(want) {
+		t.Fatalf("StdDev = %.17g, want %.17g", got, want)
+	}
+	if s.Count() != int64(len(p)) || s.Sum() != accsum.Sum2(p) {
+		t.Fatalf("Count, Sum = %d, %.17g, want %d, %.17g",
+			s.Count(), s.Sum(), len(p), accsum.Sum2(p))
+	}
+	sort.Float64s(p)
+	for _, q := range []float64{0, .05, .25, .5, .75, .9, .95, 1} {
+		want := p[int(q*float64(len(p)-1))]
+		if got := s.Quantile(q); math.Abs(got-want) > .05 {
+			t.Errorf("Quantile(%g) = %.6f, want about %.6f", q, got, want)
+		}
+	}
+}
+
+func TestReduce(t *testing.T) {
+	p := []float64{1e20, -3, 17, 1e-20, -1e20, 5}
+	sum, min, max := accsum.Reduce(p)
+	if sum != 19 || min != -1e20 || max != 1e20 {
+		t.Fatalf("Reduce = %g, %g, %g, want 19, -1e20, 1e20", sum, min, max)
+	}
+	sum, min, max = accsum.Reduce(append(p, math.NaN()))
+	if !math.IsNaN(sum) || !math.IsNaN(min) || !math.IsNaN(max) {
+		t.Fatalf("Reduce with NaN = %g, %g, %g, want all NaN", sum, min, max)
+	}
+	sum, min, max = accsum.Reduce(nil)
+	if sum != 0 || !math.IsInf(min, 1) || !math.IsInf(max, -1) {
+		t.Fatalf("Reduce(nil) = %g, %g, %g, want 0, +Inf, -Inf", sum, min, max)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	p, _, _ := accsum.GenSum(1000, 1e12)
+	got := accsum.Summarize(p)
+	abs := make([]float64, len(p))
+	for i, x := range p {
+		abs[i] = math.Abs(x)
+	}
+	_, min, max := accsum.Reduce(p)
+	want := accsum.Summary{
+		Sum:    accsum.Sum2(p),
+		Mean:   accsum.Mean(p),
+		Min:    min,
+		Max:    max,
+		SumAbs: accsum.Sum2(abs),
+		Count:  len(p),
+	}
+	if got != want {
+		t.Fatalf("Summarize = %+v\nwant        %+v", got, want)
+	}
+	got = accsum.Summarize(nil)
+	if got.Count != 0 || got.Sum != 0 || !math.IsNaN(got.Mean) ||
+		!math.IsInf(got.Min, 1) || !math.IsInf(got.Max, -1) {
+		t.Fatalf("Summarize(nil) = %+v", got)
+	}
+}
+
+func TestNanSum(t *testing.T) {
+	p := randSlice(1000)
+	var finite []float64
+	for i := range p {
+		if rand.Intn(5) == 0 {
+			p[i] = math.NaN()
+		} else {
+			finite = append(finite, p[i])
+		}
+	}
+	if got, want := accsum.NanSum(p), accsum.Sum2(finite); got != want {
+		t.Fatalf("NanSum = %.17g, want %.17g", got, want)
+	}
+	if got, want := accsum.NanMean(p), accsum.Mean(finite); got != want {
+		t.Fatalf("NanMean = %.17g, want %.17g", got, want)
+	}
+	all := []float64{math.NaN(), math.NaN()}
+	if s, m := accsum.NanSum(all), accsum.NanMean(all); s != 0 || !math.IsNaN(m) {
+		t.Fatalf("all NaN: NanSum = %g, NanMean = %g, want 0, NaN", s, m)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	p := make([]float64, 1000)
+	for i := range p {
+		p[i] = math.Ldexp(rand.Float64(), rand.Intn(200)-100)
+	}
+	q := accsum.Normalize(p)
+	if s := accsum.ExactSumSorted(q); math.Abs(s-1) > 4*0x1p-53 {
+		t.Fatalf("normalized values sum to %.17g", s)
+	}
+	for i, x := range q {
+		if x < 0 || x > 1 || (x == 0) != (p[i] == 0) {
+			t.Fatalf("Normalize element %d = %g from %g", i, x, p[i])
+		}
+	}
+	for _, x := range accsum.Normalize([]float64{1, -1}) {
+		if !math.IsNaN(x) {
+			t.Fatalf("Normalize with zero sum gave %g, want NaN", x)
+		}
+	}
+}
+
+func TestGeoMean(t *testing.T) {
+	p := make([]float64, 1000)
+	prod := new(big.Float).SetPrec(200).SetInt64(1)
+	var bx big.Float
+	for i := range p {
+		p[i] = math.Ldexp(1+rand.Float64(), rand.Intn(1200)-600)
+		prod.Mul(prod, bx.SetFloat64(p[i]))
+	}
+	var m big.Float
+	e := prod.MantExp(&m)
+	mf, _ := m.Float64()
+	want := math.Exp((math.Log(mf) + float64(e)*math.Ln2) / float64(len(p)))
+	got := accsum.GeoMean(p)
+	if r := math.Abs(got-want) / want; r > 1e-13 {
+		t.Fatalf("GeoMean = %.17g, want %.17g", got, want)
+	}
+	for _, p := range [][]float64{{1, 0, 2}, {1, -1}, {math.NaN()}, nil} {
+		if g := accsum.GeoMean(p); !math.IsNaN(g) {
+			t.Fatalf("GeoMean(%v) = %g, want NaN", p, g)
+		}
+	}
+}
+
+func TestTrimmedSum(t *testing.T) {
+	p := randSlice(100)
+	q := append([]float64{}, p...)
+	sort.Float64s(q)
+	want := accsum.Sum2(q[10:95])
+	// outliers replace values that are trimmed anyway
+	r := append([]float64{}, p...)
+	for i, x := range r {
+		switch x {
+		case q[0]:
+			r[i] = -1e300
+		case q[99]:
+			r[i] = 1e300
+		}
+	}
+	c := append([]float64{}, r...)
+	if got := accsum.TrimmedSum(r, .1, .05); got != want {
+		t.Fatalf("TrimmedSum = %.17g, want %.17g", got, want)
+	}
+	for i := range r {
+		if r[i] != c[i] {
+			t.Fatal("TrimmedSum modified its argument")
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("TrimmedSum did not panic on invalid fractions")
+		}
+	}()
+	accsum.TrimmedSum(p, .5, .5)
+}
+
+func TestCovAccumulator(t *testing.T) {
+	n := 1000
+	x := make([]float64, n)
+	y := make([]float64, n)
+	var c accsum.CovAccumulator
+	for i := range x {
+		r := rand.Float64()
+		x[i] = 1e8 + r
+		y[i] = -3e9 + .5*r + .1*rand.Float64()
+		c.Add(x[i], y[i])
+	}
+	// two-pass reference
+	const prec = 2000
+	mean := func(p []float64) *big.Float {
+		s := new(big.Float).SetPrec(prec)
+		var b big.Float
+		for _, v := range p {
+			s.Add(s, b.SetFloat64(v))
+		}
+		return s.Quo(s, big.NewFloat(float64(len(p))))
+	}
+	comoment := func(a, b []float64) *big.Float {
+		ma, mb := mean(a), mean(b)
+		s := new(big.Float).SetPrec(prec)
+		var da, db big.Float
+		da.SetPrec(prec)
+		db.SetPrec(prec)
+		for i := range a {
+			da.Sub(da.SetFloat64(a[i]), ma)
+			db.Sub(db.SetFloat64(b[i]), mb)
+			s.Add(s, da.Mul(&da, &db))
+		}
+		return s
+	}
+	cxy := comoment(x, y)
+	cov, _ := new(big.Float).Quo(cxy, big.NewFloat(float64(n-1))).Float64()
+	if got := c.Cov(); math.Abs(got-cov) > 2*ulp(cov) {
+		t.Fatalf("Cov = %.17g, want %.17g", got, cov)
+	}
+	d := new(big.Float).SetPrec(prec).Mul(comoment(x, x), comoment(y, y))
+	corr, _ := cxy.Quo(cxy, d.Sqrt(d)).Float64()
+	if got := c.Corr(); math.Abs(got-corr) > 4*ulp(corr) {
+		t.Fatalf("Corr = %.17g, want %.17g", got, corr)
+	}
+	var e accsum.CovAccumulator
+	e.Add(1, 2)
+	if !math.IsNaN(e.Cov()) || !math.IsNaN(e.Corr()) {
+		t.Fatal("Cov and Corr of one pair should be NaN")
+	}
+}
+
+func TestExpBuckets(t *testing.T) {
+	p := randSlice(1000)
+	p = append(p, 0, 1e200, -1e200)
+	b := accsum.NewExpBuckets(p)
+	want := accsum.ExactSumSorted(p)
+	if got := b.SumRange(math.MinInt32, math.MaxInt32); math.Abs(got-want) > ulp(want) {
+		t.Fatalf("SumRange of all = %.17g, want %.17g", got, want)
+	}
+	if got := b.SumRange(math.MinInt, math.MaxInt); math.Abs(got-want) > ulp(want) {
+		t.Fatalf("SumRange(MinInt, MaxInt) = %.17g, want %.17g", got, want)
+	}
+	// sum of disjoint ranges covering all exponents.  Each range sum is
+	// faithfully rounded so allow an ulp of each, which can be large
+	// relative to the total.
+	var s []float64
+	for e := -60; e < 60; e += 10 {
+		s = append(s, b.SumRange(e, e+9))
+	}
+	s = append(s, b.SumRange(math.MinInt32, -61), b.SumRange(60, math.MaxInt32))
+	tol := 2 * ulp(want)
+	for _, x := range s {
+		tol += ulp(x)
+	}
+	if got := accsum.Sum2(s); math.Abs(got-want) > tol {
+		t.Fatalf("sum of ranges = %.17g, want %.17g", got, want)
+	}
+	var band []float64
+	for _, x := range p {
+		if math.Ilogb(x) >= -5 && math.Ilogb(x) <= 5 {
+			band = append(band, x)
+		}
+	}
+	want = accsum.ExactSumSorted(band)
+	if got := b.SumRange(-5, 5); math.Abs(got-want) > ulp(want) {
+		t.Fatalf("SumRange(-5, 5) = %.17g, want %.17g", got, want)
+	}
+}
+
+func TestMomentsAccumulator(t *testing.T) {
+	// exponential distribution, offset
+	p := make([]float64, 10000)
+	var m accsum.MomentsAccumulator
+	for i := range p {
+		p[i] = 1e6 + rand.ExpFloat64()
+		m.Add(p[i])
+	}
+	// two-pass reference
+	const prec = 500
+	mean := new(big.Float).SetPrec(prec)
+	var b big.Float
+	for _, x := range p {
+		mean.Add(mean, b.SetFloat64(x))
+	}
+	n := big.NewFloat(float64(len(p)))
+	mean.Quo(mean, n)
+	var c [5]big.Float // central moment sums
+	for i := range c {
+		c[i].SetPrec(prec)
+	}
+	var d, dk big.Float
+	d.SetPrec(prec)
+	dk.SetPrec(prec)
+	for _, x := range p {
+		d.Sub(b.SetFloat64(x), mean)
+		dk.Set(&d)
+		for k := 2; k <= 4; k++ {
+			dk.Mul(&dk, &d)
+			c[k].Add(&c[k], &dk)
+		}
+	}
+	f := func(x *big.Float) float64 { v, _ := x.Float64(); return v }
+	nf := float64(len(p))
+	variance := f(new(big.Float).Quo(&c[2], big.NewFloat(nf-1)))
+	var s big.Float
+	s.SetPrec(prec).Mul(&c[2], &c[2])
+	s.Quo(&c[4], &s).Mul(&s, n)
+	kurt := f(&s) - 3
+	// skewness = M3 / sqrt(M2^3 / n)
+	s.Mul(&c[2], &c[2]).Mul(&s, &c[2]).Quo(&s, n).Sqrt(&s)
+	skew := f(s.Quo(&c[3], &s))
+	for _, r := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"Variance", m.Variance(), variance},
+		{"Skewness", m.Skewness(), skew},
+		{"Kurtosis", m.Kurtosis(), kurt},
+	} {
+		if math.Abs(r.got-r.want) > 8*ulp(r.want) {
+			t.Errorf("%s = %.17g, want %.17g", r.name, r.got, r.want)
+		}
+	}
+}
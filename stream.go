@@ -0,0 +1,379 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum
+
+// Stream.go:  Accumulators for values arriving one at a time.
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Accumulator accumulates a sum of values, as if computed in twice the
+// precision of a float64.
+//
+// Values are added as in Sum2, with a running sum and a separate
+// compensation term.  Over very long streams the compensation term can
+// itself accumulate rounding errors.  To prevent slow drift, the pair can be
+// renormalized, re-expressed as a canonical pair with TwoSum, either on
+// demand with Renorm or automatically as set with SetRenorm.
+//
+// The zero value is an empty sum ready to use, with no automatic
+// renormalization.
+type Accumulator struct {
+	sum, comp float64
+	n         int64 // number of values added
+	every     int64 // automatic renormalization period, 0 for none
+}
+
+// Add adds x to the sum.
+func (a *Accumulator) Add(x float64) {
+	var y float64
+	a.sum, y = TwoSum(a.sum, x)
+	a.comp += y
+	if a.n++; a.every > 0 && a.n%a.every == 0 {
+		a.Renorm()
+	}
+}
+
+// Sum returns the sum of values added so far.
+func (a *Accumulator) Sum() float64 {
+	return a.sum + a.comp
+}
+
+// Count returns the number of values added so far.
+func (a *Accumulator) Count() int64 {
+	return a.n
+}
+
+// CompMagnitude returns the magnitude of the current compensation term.
+//
+// The compensation term accumulates the rounding errors of the running sum.
+// A magnitude that is large relative to that of Sum indicates heavy
+// cancellation in the values added.  Renormalization, by Renorm or
+// automatically, reduces the compensation term to the rounding error of the
+// sum.
+func (a *Accumulator) CompMagnitude() float64 {
+	return math.Abs(a.comp)
+}
+
+// Renorm renormalizes the accumulator.
+//
+// The value of the sum is unchanged, but the compensation term is reduced
+// to the rounding error of the running sum.
+func (a *Accumulator) Renorm() {
+	a.sum, a.comp = TwoSum(a.sum, a.comp)
+}
+
+// SetRenorm sets the accumulator to renormalize automatically after every
+// n values added.  N of 0 disables automatic renormalization.
+func (a *Accumulator) SetRenorm(n int) {
+	a.every = int64(n)
+}
+
+// accumulatorLen is the length of the binary encoding of an Accumulator.
+const accumulatorLen = 32
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+//
+// The encoding holds the running sum and compensation term as their IEEE 754
+// bits, the count of values added, and the renormalization period set with
+// SetRenorm, each as 8 bytes little endian.  An Accumulator restored with
+// UnmarshalBinary thus continues exactly as the original would have.
+func (a *Accumulator) MarshalBinary() ([]byte, error) {
+	b := make([]byte, accumulatorLen)
+	binary.LittleEndian.PutUint64(b, math.Float64bits(a.sum))
+	binary.LittleEndian.PutUint64(b[8:], math.Float64bits(a.comp))
+	binary.LittleEndian.PutUint64(b[16:], uint64(a.n))
+	binary.LittleEndian.PutUint64(b[24:], uint64(a.every))
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, restoring the state
+// encoded by MarshalBinary.
+func (a *Accumulator) UnmarshalBinary(b []byte) error {
+	if len(b) != accumulatorLen {
+		return fmt.Errorf("Accumulator.UnmarshalBinary: len(b) = %d, want %d",
+			len(b), accumulatorLen)
+	}
+	a.sum = math.Float64frombits(binary.LittleEndian.Uint64(b))
+	a.comp = math.Float64frombits(binary.LittleEndian.Uint64(b[8:]))
+	a.n = int64(binary.LittleEndian.Uint64(b[16:]))
+	a.every = int64(binary.LittleEndian.Uint64(b[24:]))
+	return nil
+}
+
+// RemovableSum maintains a sum of a changing collection of values, as if
+// computed in twice the precision of a float64.
+//
+// Values are added and removed with TwoSum as in Accumulator.  Every
+// removableRenorm updates, the sum and compensation term are renormalized
+// as with Accumulator.Renorm.
+//
+// The error bound is that of Sum2 over all values ever added and removed.
+// After many updates it can thus be large relative to a current sum that is
+// small.  RemovableSum does not keep the values, so it cannot check that a
+// removed value was previously added.
+//
+// The zero value is an empty sum ready to use.
+type RemovableSum struct {
+	sum, comp float64
+	n         int // updates since renormalization
+}
+
+// number of updates between renormalizations of a RemovableSum
+const removableRenorm = 1024
+
+// Add adds x to the sum.
+func (r *RemovableSum) Add(x float64) {
+	var y float64
+	r.sum, y = TwoSum(r.sum, x)
+	r.comp += y
+	if r.n++; r.n == removableRenorm {
+		r.sum, r.comp = TwoSum(r.sum, r.comp)
+		r.n = 0
+	}
+}
+
+// Remove removes x, a value previously added, from the sum.
+func (r *RemovableSum) Remove(x float64) {
+	r.Add(-x)
+}
+
+// Sum returns the sum of values currently in the collection.
+func (r *RemovableSum) Sum() float64 {
+	return r.sum + r.comp
+}
+
+// DotAccumulator accumulates a dot product from pairs of values, as if
+// computed in twice the precision of a float64.
+//
+// The zero value is an empty dot product ready to use.
+type DotAccumulator struct {
+	s, e float64
+}
+
+// Add adds the product a*b to the dot product.
+//
+// The product is formed error-free with TwoProduct and accumulated as in
+// Dot2.
+func (d *DotAccumulator) Add(a, b float64) {
+	h, r := TwoProduct(a, b)
+	var q float64
+	d.s, q = TwoSum(d.s, h)
+	d.e += q + r
+}
+
+// Dot returns the dot product of values added so far.
+func (d *DotAccumulator) Dot() float64 {
+	return d.s + d.e
+}
+
+// ProdAccumulator accumulates a product of values, as if computed in twice
+// the precision of a float64 and with unbounded exponent range.
+//
+// The running product is kept as a double-length mantissa, with the error
+// of each multiplication captured with TwoProduct, and a separate binary
+// exponent.  Intermediate products therefore neither overflow nor
+// underflow, as can happen for example with long products of
+// probabilities.
+//
+// The zero value is an empty product, with value 1, ready to use.
+type ProdAccumulator struct {
+	h, l float64 // mantissa h+l, |h| in [.5, 1)
+	exp  int
+	n    int64 // number of values multiplied
+}
+
+// Add multiplies the product by x.
+func (pa *ProdAccumulator) Add(x float64) {
+	if pa.n == 0 {
+		pa.h = .5
+		pa.exp = 1
+	}
+	pa.n++
+	f, e := math.Frexp(x)
+	if f == 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		pa.h *= f
+		pa.l = 0
+		return
+	}
+	if pa.h == 0 || math.IsInf(pa.h, 0) || math.IsNaN(pa.h) {
+		pa.h *= f
+		return
+	}
+	p, r := TwoProduct(pa.h, f)
+	pa.h, pa.l = FastTwoSum(p, r+pa.l*f)
+	f, e2 := math.Frexp(pa.h)
+	pa.h = f
+	pa.l = math.Ldexp(pa.l, -e2)
+	pa.exp += e + e2
+}
+
+// Frexp returns the product as a fraction frac and exponent exp such that
+// the product equals frac × 2^exp, with the absolute value of frac in the
+// interval [½, 1).
+//
+// Frac is rounded to float64 but exp is not limited to the exponent range
+// of float64.  If the product is zero, infinite, or NaN, frac is that value
+// and exp is 0.
+func (pa *ProdAccumulator) Frexp() (frac float64, exp int) {
+	if pa.n == 0 {
+		return .5, 1
+	}
+	frac, e := math.Frexp(pa.h + pa.l)
+	if frac == 0 || math.IsInf(frac, 0) || math.IsNaN(frac) {
+		return frac, 0
+	}
+	return frac, pa.exp + e
+}
+
+// Value returns the product rounded to float64.
+//
+// The result overflows or underflows only if the final product is outside
+// the range of float64.
+func (pa *ProdAccumulator) Value() float64 {
+	return math.Ldexp(pa.Frexp())
+}
+
+// SumChan returns a sum of values received from ch, as if computed in twice
+// the precision of a float64.
+//
+// SumChan receives until ch is closed, then returns the sum and a nil error.
+// If ctx is cancelled first, SumChan returns the sum of values received so
+// far and ctx.Err().
+func SumChan(ctx context.Context, ch <-chan float64) (float64, error) {
+	var s, e, y float64
+	for {
+		select {
+		case <-ctx.Done():
+			return s + e, ctx.Err()
+		case x, ok := <-ch:
+			if !ok {
+				return s + e, nil
+			}
+			s, y = TwoSum(s, x)
+			e += y
+		}
+	}
+}
+
+// WindowSum maintains a sum over a sliding window of the most recent values.
+//
+// The sum is computed as if in twice the precision of a float64.  Values
+// entering and leaving the window are added and subtracted with TwoSum.
+// To keep accumulated rounding of the error term from drifting, the sum is
+// recomputed from the window contents each time the window has been
+// completely replaced, at an amortized cost of one TwoSum per value.
+// Accuracy thus does not degrade however many values are pushed.
+//
+// The zero value is not usable.  Create a WindowSum with NewWindowSum.
+type WindowSum struct {
+	w    []float64 // ring buffer
+	i    int       // next position in w
+	full bool
+	s, e float64
+}
+
+// NewWindowSum returns a WindowSum with window size n.  NewWindowSum panics
+// if n is not positive.
+func NewWindowSum(n int) *WindowSum {
+	if n <= 0 {
+		panic(fmt.Sprintf("window size %d, must be positive", n))
+	}
+	return &WindowSum{w: make([]float64, n)}
+}
+
+// Push adds x to the window, dropping the oldest value if the window is
+// full, and returns the sum of values in the window.
+func (ws *WindowSum) Push(x float64) float64 {
+	var y float64
+	if ws.full {
+		ws.s, y = TwoSum(ws.s, -ws.w[ws.i])
+		ws.e += y
+	}
+	ws.s, y = TwoSum(ws.s, x)
+	ws.e += y
+	ws.w[ws.i] = x
+	if ws.i++; ws.i == len(ws.w) {
+		ws.i = 0
+		ws.full = true
+		ws.s, ws.e = 0, 0
+		for _, x := range ws.w {
+			ws.s, y = TwoSum(ws.s, x)
+			ws.e += y
+		}
+	}
+	return ws.s + ws.e
+}
+
+// SumReader returns a sum of float64 values read from r, as if computed in
+// twice the precision of a float64.
+//
+// Values are decoded from successive 8 byte IEEE 754 binary representations
+// in the byte order given by order, binary.LittleEndian or binary.BigEndian
+// for example.  Reading continues until io.EOF, which is not reported as an
+// error.  If the data ends with a partial value, the sum of complete values
+// is returned with io.ErrUnexpectedEOF.  On any other read error, the sum of
+// values read so far is returned with the error.
+func SumReader(r io.Reader, order binary.ByteOrder) (float64, error) {
+	var s, e, y float64
+	buf := make([]byte, 8*512)
+	for {
+		n, err := io.ReadFull(r, buf)
+		for i := 0; i+8 <= n; i += 8 {
+			s, y = TwoSum(s, math.Float64frombits(order.Uint64(buf[i:])))
+			e += y
+		}
+		switch {
+		case err == nil:
+			continue
+		case err == io.EOF:
+			err = nil
+		case err == io.ErrUnexpectedEOF && n%8 == 0:
+			err = nil
+		}
+		return s + e, err
+	}
+}
+
+// SumCSVColumn returns a sum of the values in column col of CSV data read
+// from r, as if computed in twice the precision of a float64.
+//
+// Columns are numbered from 0.  Each field is parsed with
+// strconv.ParseFloat.  If the field of the first record does not parse, it
+// is taken as a header and skipped.  On any later parse error, a record
+// lacking column col, or a read error, SumCSVColumn returns the sum of values
+// so far with an error identifying the record, numbered from 1.
+func SumCSVColumn(r io.Reader, col int) (float64, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	var s, e, y float64
+	for row := 1; ; row++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return s + e, nil
+		}
+		if err != nil {
+			return s + e, err
+		}
+		if col < 0 || col >= len(rec) {
+			return s + e, fmt.Errorf("record %d: no column %d", row, col)
+		}
+		x, err := strconv.ParseFloat(rec[col], 64)
+		if err != nil {
+			if row == 1 {
+				continue // header
+			}
+			return s + e, fmt.Errorf("record %d: %w", row, err)
+		}
+		s, y = TwoSum(s, x)
+		e += y
+	}
+}
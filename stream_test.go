@@ -0,0 +1,354 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/soniakeys/accsum"
+)
+
+func TestAccumulator(t *testing.T) {
+	n := int64(1e8)
+	if testing.Short() {
+		n = 1e6
+	}
+	// Values all have exponent -4 so each is an integer multiple of 2^-56
+	// and the exact sum can be kept as an integer.
+	r := rand.New(rand.NewSource(1))
+	var a, plain accsum.Accumulator
+	a.SetRenorm(1000)
+	var exact int64
+	for i := int64(0); i < n; i++ {
+		x := .1 + r.Float64()*1e-3
+		if i%2 == 1 {
+			x = -x * (1 - 1e-9)
+		}
+		a.Add(x)
+		plain.Add(x)
+		exact += int64(math.Ldexp(x, 56))
+	}
+	want := math.Ldexp(float64(exact), -56)
+	if got := a.Sum(); math.Abs(got-want) > ulp(want) {
+		t.Fatalf("Sum = %.17g, want %.17g", got, want)
+	}
+	if a.Count() != n {
+		t.Fatalf("Count = %d, want %d", a.Count(), n)
+	}
+	// state returns the running sum and compensation term of c.
+	state := func(c *accsum.Accumulator) (sum, comp float64) {
+		b, err := c.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)),
+			math.Float64frombits(binary.LittleEndian.Uint64(b[8:]))
+	}
+	// n is a multiple of the renormalization period, so the pair was just
+	// renormalized and should be canonical.  Without renormalization the
+	// compensation term grows well beyond the rounding error of the sum.
+	if sum, comp := state(&a); sum != sum+comp {
+		t.Fatalf("after automatic renormalization sum, comp = %g, %g", sum, comp)
+	}
+	if sum, comp := state(&plain); sum == sum+comp {
+		t.Fatalf("without renormalization sum, comp = %g, %g, want comp"+
+			" not canonical", sum, comp)
+	}
+	a.Add(1e-20)
+	a.Add(.3)
+	s := a.Sum()
+	a.Renorm()
+	if a.Sum() != s {
+		t.Fatalf("Sum after Renorm = %.17g, want %.17g", a.Sum(), s)
+	}
+	if sum, comp := state(&a); sum != sum+comp {
+		t.Fatalf("after Renorm sum, comp = %g, %g", sum, comp)
+	}
+}
+
+func TestAccumulatorMarshal(t *testing.T) {
+	p, _, _ := accsum.GenSum(1000, 1e20)
+	var whole, first accsum.Accumulator
+	whole.SetRenorm(100)
+	first.SetRenorm(100)
+	for _, x := range p {
+		whole.Add(x)
+	}
+	for _, x := range p[:len(p)/2] {
+		first.Add(x)
+	}
+	b, err := first.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored accsum.Accumulator
+	if err := restored.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range p[len(p)/2:] {
+		restored.Add(x)
+	}
+	if restored != whole {
+		t.Fatalf("restored = %+v, want %+v", restored, whole)
+	}
+	if err := restored.UnmarshalBinary(b[1:]); err == nil {
+		t.Fatal("UnmarshalBinary of short data returned nil error")
+	}
+}
+
+func TestCompMagnitude(t *testing.T) {
+	var benign, cancel accsum.Accumulator
+	cancel.Add(1e16)
+	for i := 1; i <= 1000; i++ {
+		benign.Add(float64(i))
+		cancel.Add(1)
+	}
+	cancel.Add(-1e16)
+	if c := benign.CompMagnitude(); c != 0 {
+		t.Fatalf("benign stream CompMagnitude = %g, want 0", c)
+	}
+	if c := cancel.CompMagnitude(); c < 100 {
+		t.Fatalf("cancelling stream CompMagnitude = %g, want large", c)
+	}
+	if s := cancel.Sum(); s != 1000 {
+		t.Fatalf("cancelling stream Sum = %g, want 1000", s)
+	}
+}
+
+func TestRemovableSum(t *testing.T) {
+	var rs accsum.RemovableSum
+	var set []float64
+	abs := 0. // sum of magnitudes of all updates
+	for i := 0; i < 100000; i++ {
+		if len(set) > 0 && rand.Intn(2) == 0 {
+			j := rand.Intn(len(set))
+			rs.Remove(set[j])
+			abs += math.Abs(set[j])
+			set[j] = set[len(set)-1]
+			set = set[:len(set)-1]
+		} else {
+			x := math.Ldexp(rand.Float64()*2-1, rand.Intn(100)-50)
+			rs.Add(x)
+			abs += math.Abs(x)
+			set = append(set, x)
+		}
+		if i%1000 == 0 {
+			// error bound of Sum2 over all updates
+			n := float64(i + 1)
+			γ := n * 0x1p-53 / (1 - n*0x1p-53)
+			want := accsum.Sum2(set)
+			tol := 2*ulp(want) + γ*γ*abs
+			if got := rs.Sum(); math.Abs(got-want) > tol {
+				t.Fatalf("after %d updates, Sum = %.17g, want %.17g",
+					i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestDotAccumulator(t *testing.T) {
+	x, y, _, _ := accsum.GenDot(100, 1e20)
+	var d accsum.DotAccumulator
+	for i, xi := range x {
+		d.Add(xi, y[i])
+		if got, want := d.Dot(), accsum.Dot2(x[:i+1], y[:i+1]); got != want {
+			t.Fatalf("after %d pairs, Dot = %.17g, want %.17g", i+1, got, want)
+		}
+	}
+}
+
+func TestProdAccumulator(t *testing.T) {
+	var pa accsum.ProdAccumulator
+	if v := pa.Value(); v != 1 {
+		t.Fatalf("empty product = %g, want 1", v)
+	}
+	want := new(big.Float).SetPrec(300).SetInt64(1)
+	var b big.Float
+	naive := 1.
+	mul := func(x float64) {
+		pa.Add(x)
+		want.Mul(want, b.SetFloat64(x))
+		naive *= x
+	}
+	for i := 0; i < 10000; i++ {
+		mul(.5 * (1 + rand.Float64()*1e-3))
+	}
+	var m big.Float
+	we := want.MantExp(&m)
+	wf, _ := m.Float64()
+	if f, e := pa.Frexp(); e != we || math.Abs(f-wf) > 2*ulp(wf) {
+		t.Fatalf("Frexp = %.17g, %d, want %.17g, %d", f, e, wf, we)
+	}
+	// bring the product back into range
+	for i := 0; i < 10000; i++ {
+		mul(2 * (1 - rand.Float64()*1e-3))
+	}
+	w, _ := want.Float64()
+	if got := pa.Value(); math.Abs(got-w) > 2*ulp(w) {
+		t.Fatalf("Value = %.17g, want %.17g", got, w)
+	}
+	if math.Abs(naive-w) < 1e-3*w {
+		t.Fatalf("naive product %g did not underflow", naive)
+	}
+	pa.Add(0)
+	if v := pa.Value(); v != 0 {
+		t.Fatalf("product with 0 = %g, want 0", v)
+	}
+}
+
+func TestSumChan(t *testing.T) {
+	p, _, _ := accsum.GenSum(200, 1e12)
+	ch := make(chan float64)
+	ctx, cancel := context.WithCancel(context.Background())
+	type result struct {
+		s   float64
+		err error
+	}
+	rc := make(chan result)
+	go func() {
+		s, err := accsum.SumChan(ctx, ch)
+		rc <- result{s, err}
+	}()
+	n := 150
+	for _, x := range p[:n] {
+		ch <- x
+	}
+	cancel()
+	r := <-rc
+	if r.err != context.Canceled {
+		t.Fatalf("SumChan error = %v, want %v", r.err, context.Canceled)
+	}
+	if want := accsum.Sum2(p[:n]); r.s != want {
+		t.Fatalf("SumChan partial = %.17g, want %.17g", r.s, want)
+	}
+
+	// uncancelled, to completion
+	ch = make(chan float64)
+	go func() {
+		for _, x := range p {
+			ch <- x
+		}
+		close(ch)
+	}()
+	s, err := accsum.SumChan(context.Background(), ch)
+	if want := accsum.Sum2(p); s != want || err != nil {
+		t.Fatalf("SumChan = %.17g, %v, want %.17g, nil", s, err, want)
+	}
+}
+
+func TestWindowSum(t *testing.T) {
+	n := 100
+	ws := accsum.NewWindowSum(n)
+	var p []float64
+	for i := 0; i < 100000; i++ {
+		x := rand.Float64()
+		if rand.Intn(50) == 0 {
+			x *= 1e15
+		}
+		p = append(p, x)
+		w := p
+		if len(w) > n {
+			w = w[len(w)-n:]
+		}
+		got := ws.Push(x)
+		// Since the last recomputation the running sum has seen up to 3n
+		// TwoSums over the last 2n values, so allow the Sum2 error bound
+		// for that, eps*|sum| + γ(3n)²*Σ|x|.
+		h := p
+		if len(h) > 2*n {
+			h = h[len(h)-2*n:]
+		}
+		var abs float64
+		for _, x := range h {
+			abs += math.Abs(x)
+		}
+		γ := 3 * float64(n) * 0x1p-53 / (1 - 3*float64(n)*0x1p-53)
+		// want is faithful, so allow an ulp for it as well.
+		want := accsum.AccSum(append([]float64{}, w...))
+		if math.Abs(got-want) > 2*ulp(want)+γ*γ*abs {
+			t.Fatalf("push %d: WindowSum = %.17g, want %.17g", i, got, want)
+		}
+	}
+	ws = accsum.NewWindowSum(1)
+	for _, x := range []float64{1, 2, 3} {
+		if got := ws.Push(x); got != x {
+			t.Fatalf("window size 1: Push(%g) = %g", x, got)
+		}
+	}
+	for _, n := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("NewWindowSum(%d) did not panic", n)
+				}
+			}()
+			accsum.NewWindowSum(n)
+		}()
+	}
+}
+
+func TestSumReader(t *testing.T) {
+	p, _, _ := accsum.GenSum(2000, 1e10) // more than one buffer full
+	want := accsum.Sum2(p)
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		var b bytes.Buffer
+		binary.Write(&b, order, p)
+		data := b.Bytes()
+		s, err := accsum.SumReader(bytes.NewReader(data), order)
+		if s != want || err != nil {
+			t.Fatalf("%v: SumReader = %.17g, %v, want %.17g, nil",
+				order, s, err, want)
+		}
+		// truncated in the middle of the last value
+		s, err = accsum.SumReader(bytes.NewReader(data[:len(data)-3]), order)
+		if w := accsum.Sum2(p[:len(p)-1]); s != w || err != io.ErrUnexpectedEOF {
+			t.Fatalf("%v: SumReader = %.17g, %v, want %.17g, %v",
+				order, s, err, w, io.ErrUnexpectedEOF)
+		}
+	}
+	if s, err := accsum.SumReader(bytes.NewReader(nil), binary.BigEndian); s != 0 || err != nil {
+		t.Fatalf("SumReader on empty = %g, %v, want 0, nil", s, err)
+	}
+}
+
+func TestSumCSVColumn(t *testing.T) {
+	p := randSlice(100)
+	var b strings.Builder
+	b.WriteString("id,value\n")
+	for i, x := range p {
+		fmt.Fprintf(&b, "%d,%s\n", i, strconv.FormatFloat(x, 'g', -1, 64))
+	}
+	s, err := accsum.SumCSVColumn(strings.NewReader(b.String()), 1)
+	if want := accsum.Sum2(p); s != want || err != nil {
+		t.Fatalf("SumCSVColumn = %.17g, %v, want %.17g, nil", s, err, want)
+	}
+	// no header
+	s, err = accsum.SumCSVColumn(strings.NewReader("1,2\n3,4\n"), 0)
+	if s != 4 || err != nil {
+		t.Fatalf("SumCSVColumn = %g, %v, want 4, nil", s, err)
+	}
+	// malformed cell in record 3
+	s, err = accsum.SumCSVColumn(strings.NewReader("x\n1.5\n2x\n4\n"), 0)
+	if s != 1.5 || err == nil || !strings.Contains(err.Error(), "record 3") {
+		t.Fatalf("SumCSVColumn = %g, %v, want 1.5 and error at record 3", s, err)
+	}
+	var ne *strconv.NumError
+	if !errors.As(err, &ne) {
+		t.Fatalf("error %v does not wrap *strconv.NumError", err)
+	}
+	if _, err = accsum.SumCSVColumn(strings.NewReader("1,2\n3\n"), 1); err == nil {
+		t.Fatal("SumCSVColumn did not report missing column")
+	}
+}
@@ -8,8 +8,11 @@ package accsum
 // code, or otherwise of interest.
 
 import (
+	"fmt"
 	"math"
+	"math/bits"
 	"sort"
+	"time"
 )
 
 // Sum returns a sum of the values in p.
@@ -88,6 +91,47 @@ func XDot(x, y []float64) float64 {
 	return s
 }
 
+// KahanDot returns a dot product of the values in x and y.
+//
+// Products are formed in ordinary floating point arithmetic and summed with
+// Kahan's compensated summation, as in KahanSum.  This removes the error of
+// accumulation that dominates Dot for long vectors but not the rounding
+// errors of the products themselves.  KahanDot is thus a middle option,
+// more accurate than Dot and cheaper than Dot2.
+//
+// X and y must be of the same length, panic or nonsense results otherwise.
+//
+// KahanDot performs 5*len(x) floating point operations.
+func KahanDot(x, y []float64) float64 {
+	var s, c float64
+	for i, xi := range x {
+		v := xi*y[i] - c
+		t := s + v
+		c = t - s - v
+		s = t
+	}
+	return s
+}
+
+// CosSum returns a sum of the terms a[k]*cos(k*θ), as if computed in twice
+// the precision of a float64.
+//
+// Each cosine is computed once with math.Cos and each product is formed
+// error-free with TwoProduct.  The products and their errors are then
+// summed as in Dot2.  Accuracy is limited by the cosines themselves:
+// k*θ is rounded to float64 before math.Cos is called and math.Cos is not
+// correctly rounded, so for large k*θ each cosine may differ from the exact
+// cos(k*θ) by considerably more than the error of the summation.
+func CosSum(a []float64, θ float64) float64 {
+	var s, e, q float64
+	for k, ak := range a {
+		h, r := TwoProduct(ak, math.Cos(float64(k)*θ))
+		s, q = TwoSum(s, h)
+		e += q + r
+	}
+	return s + e
+}
+
 // KahanSum returns a sum of the values in p.
 //
 // The algoithm is Kahan (1965), often termed "compensated" summation.
@@ -105,6 +149,24 @@ func KahanSum(p []float64) float64 {
 	return s
 }
 
+// KahanState continues a Kahan summation of values in p, starting from
+// a running sum s and compensation c.
+//
+// Results sum and comp are the running sum and compensation after adding
+// values in p.  They can be passed as s and c to a subsequent call to
+// continue the summation.  Starting from s = 0, c = 0 and summing a slice in
+// one or more pieces, the final sum is the result of KahanSum on the whole
+// slice.
+func KahanState(p []float64, s, c float64) (sum, comp float64) {
+	for _, x := range p {
+		y := x - c
+		t := s + y
+		c = t - s - y
+		s = t
+	}
+	return s, c
+}
+
 // KahanB computes a sum of the values in p.
 //
 // The algorithm is Kahan-Babuška-Neumaier, sometimes termed a "balancing
@@ -113,8 +175,16 @@ func KahanSum(p []float64) float64 {
 // It performs 7 * len(p) + 1 floating point operations (addition, subtraction,
 // Abs, and comparison.)
 func KahanB(p []float64) float64 {
-	s := p[0]
-	c := 0.
+	s, c := kahanB(p)
+	return s + c
+}
+
+// kahanB returns the sum and compensation of KahanB.
+func kahanB(p []float64) (s, c float64) {
+	if len(p) == 0 {
+		return
+	}
+	s = p[0]
 	for _, x := range p[1:] {
 		t := s + x
 		if math.Abs(s) >= math.Abs(x) {
@@ -124,9 +194,72 @@ func KahanB(p []float64) float64 {
 		}
 		s = t
 	}
+	return
+}
+
+// PairKahanSum computes a sum of the values in p.
+//
+// The algorithm is pairwise summation with KahanB summation of blocks at the
+// base of the recursion.  Pairs of partial sums are combined with TwoSum and
+// their compensations carried up the recursion, so that no error is
+// introduced in combining.
+func PairKahanSum(p []float64) float64 {
+	s, c := pks2(p)
+	return s + c
+}
+
+// block size for PairKahanSum base case
+const pairKahanBlock = 128
+
+func pks2(p []float64) (s, c float64) {
+	if len(p) <= pairKahanBlock {
+		return kahanB(p)
+	}
+	m := len(p) / 2
+	s1, c1 := pks2(p[:m])
+	s2, c2 := pks2(p[m:])
+	s, y := TwoSum(s1, s2)
+	return s, c1 + c2 + y
+}
+
+// TreeSum returns a sum of the values in p, as if computed in twice the
+// precision of a float64.
+//
+// The algorithm generalizes pairwise summation to a reduction tree where
+// each node has up to radix children.  Radix 2 is the tree of PairSum;
+// higher radix gives a shallower tree with wider nodes.  At every node,
+// children are combined with TwoSum and compensations carried up the tree as
+// in PairKahanSum.
+//
+// TreeSum panics if radix is less than 2.
+func TreeSum(p []float64, radix int) float64 {
+	if radix < 2 {
+		panic(fmt.Sprintf("radix = %d, must be at least 2", radix))
+	}
+	s, c := ts2(p, radix)
 	return s + c
 }
 
+func ts2(p []float64, radix int) (s, c float64) {
+	var y float64
+	if len(p) <= radix {
+		for _, x := range p {
+			s, y = TwoSum(s, x)
+			c += y
+		}
+		return
+	}
+	// divide p into radix parts of nearly equal length
+	for i, lo := 1, 0; i <= radix; i++ {
+		hi := i * len(p) / radix
+		si, ci := ts2(p[lo:hi], radix)
+		s, y = TwoSum(s, si)
+		c += ci + y
+		lo = hi
+	}
+	return
+}
+
 // PriestSum computes a sum of the values in p.
 //
 // Algorithm following Matlab code PriestSum.m by S.M. Rump.  This is Priest's
@@ -150,6 +283,42 @@ func PriestSum(p []float64) float64 {
 	return s
 }
 
+// AuditSum returns the sum of values in p correctly rounded to the nearest
+// float64, independent of the order of values in p.
+//
+// A copy of p is sorted by decreasing magnitude and summed with NearSum.
+// Correct rounding alone makes the result depend only on the values in p
+// and not their order; sorting additionally makes the computation itself
+// independent of order, as may be required for auditing.  If p contains
+// NaNs, the result is the NaN with the least bit pattern, regardless of
+// order.
+//
+// Time complexity is O(n log n) in len(p).  AuditSum is not destructive on
+// p.
+func AuditSum(p []float64) float64 {
+	var nan uint64
+	hasNaN := false
+	for _, x := range p {
+		if b := math.Float64bits(x); x != x && (!hasNaN || b < nan) {
+			nan = b
+			hasNaN = true
+		}
+	}
+	if hasNaN {
+		return math.Float64frombits(nan)
+	}
+	// Sort by decreasing magnitude, ties broken by bit pattern so that
+	// x and -x, and +0 and -0, are also ordered independently of p.
+	q := append([]float64{}, p...)
+	sort.Slice(q, func(i, j int) bool {
+		if a, b := math.Abs(q[i]), math.Abs(q[j]); a != b {
+			return a > b
+		}
+		return math.Float64bits(q[i]) < math.Float64bits(q[j])
+	})
+	return NearSum(q)
+}
+
 // a type for sorting by decreasing magnitude
 type priest []float64
 
@@ -169,6 +338,26 @@ func CondSum(f func([]float64) float64, s []float64) float64 {
 	return f(c) / absSum
 }
 
+// CondInPlace computes the condition number of the sum of values in p.
+//
+// The result is the same as CondSum(AccSum, p), but CondInPlace uses the
+// caller supplied slice scratch in place of an internal copy of p, and so
+// does not allocate.  Scratch must have length at least len(p), CondInPlace
+// panics otherwise.  Scratch is overwritten, p is not modified.
+func CondInPlace(p, scratch []float64) float64 {
+	if len(scratch) < len(p) {
+		panic(fmt.Sprintf("len(scratch) = %d, want at least len(p) = %d",
+			len(scratch), len(p)))
+	}
+	c := scratch[:len(p)]
+	copy(c, p)
+	absSum := math.Abs(AccSum(c))
+	for i, x := range p {
+		c[i] = math.Abs(x)
+	}
+	return AccSum(c) / absSum
+}
+
 // CondDot computes the condition number of dot product function f over slices
 // x and y.
 func CondDot(f func(x, y []float64) float64, x, y []float64) float64 {
@@ -181,3 +370,529 @@ func CondDot(f func(x, y []float64) float64, x, y []float64) float64 {
 	}
 	return 2 * f(cx, cy) / absDot
 }
+
+// SumDiag returns an accurate sum of values in p and an estimate of the
+// number of bits of precision lost by the simple sum computed by Sum.
+//
+// The sum is computed with AccSum on a copy of p and so is a faithful
+// rounding.  LostBits is log2 of the condition number of the sum, rounded up
+// and limited to the range 0 to 53.  A result of 53 means the simple sum may
+// have no correct bits at all.
+//
+// SumDiag is not destructive on p.
+func SumDiag(p []float64) (sum float64, lostBits int) {
+	sum = AccSum(append([]float64{}, p...))
+	abs := 0.
+	for _, x := range p {
+		abs += math.Abs(x)
+	}
+	switch {
+	case abs == 0:
+		return
+	case sum == 0:
+		return sum, P
+	}
+	lostBits = int(math.Ceil(math.Log2(abs / math.Abs(sum))))
+	switch {
+	case lostBits < 0:
+		lostBits = 0
+	case lostBits > P:
+		lostBits = P
+	}
+	return
+}
+
+// DotInt returns the dot product of integer vectors x and y, correctly
+// rounded to the nearest float64.
+//
+// Each integer is split exactly into high and low parts representable as
+// float64s, the partial products are formed error-free with TwoProduct, and
+// the resulting terms summed with NearSum.  Bits are lost neither in
+// conversion to float64 nor in multiplication.
+//
+// X and y must be of the same length, panic or nonsense results otherwise.
+func DotInt(x, y []int64) float64 {
+	p := make([]float64, 0, 8*len(x))
+	for i, xi := range x {
+		xh, xl := splitInt(xi)
+		yh, yl := splitInt(y[i])
+		for _, a := range [...]float64{xh, xl} {
+			for _, b := range [...]float64{yh, yl} {
+				h, r := TwoProduct(a, b)
+				p = append(p, h, r)
+			}
+		}
+	}
+	return NearSum(p)
+}
+
+// DotMixed returns a dot product of float32 values x and float64 values y,
+// as if computed in twice the precision of a float64.
+//
+// Conversion of x to float64 is exact.  Products are then formed
+// error-free with TwoProduct and summed as in Dot2.
+//
+// DotMixed panics if x and y differ in length.
+func DotMixed(x []float32, y []float64) float64 {
+	if len(y) != len(x) {
+		panic(fmt.Sprintf("len(y) = %d, want len(x) = %d", len(y), len(x)))
+	}
+	var s, e, q float64
+	for i, xi := range x {
+		h, r := TwoProduct(float64(xi), y[i])
+		s, q = TwoSum(s, h)
+		e += q + r
+	}
+	return s + e
+}
+
+// DotPerm returns a dot product of the elements of x and y selected by
+// perm, as if computed in twice the precision of a float64.
+//
+// The result is that of Dot2 on slices gathered from x and y in the order
+// given by perm, but no gathered copies are made.  This is useful for
+// example with data in bit-reversed order.
+//
+// DotPerm panics if any element of perm is out of range for x or y.
+func DotPerm(x, y []float64, perm []int) float64 {
+	n := len(x)
+	if len(y) < n {
+		n = len(y)
+	}
+	for i, j := range perm {
+		if j < 0 || j >= n {
+			panic(fmt.Sprintf("perm[%d] = %d out of range, len(x) = %d, len(y) = %d",
+				i, j, len(x), len(y)))
+		}
+	}
+	if len(perm) == 0 {
+		return 0
+	}
+	q := 0.
+	p, s := TwoProduct(x[perm[0]], y[perm[0]])
+	for _, j := range perm[1:] {
+		h, r := TwoProduct(x[j], y[j])
+		p, q = TwoSum(p, h)
+		s += q + r
+	}
+	return p + s
+}
+
+// FMASum returns c + Σ a[i]*b[i], as if computed in twice the precision of
+// a float64.
+//
+// C is the initial value of the compensated sum, so it participates in the
+// accumulation along with the products, formed error-free with TwoProduct,
+// rather than being added to a rounded dot product.  The result is more
+// accurate than c + Dot2(a, b) when c nearly cancels the dot product.
+//
+// A and b must be of the same length, panic or nonsense results otherwise.
+func FMASum(a, b []float64, c float64) float64 {
+	var e, q float64
+	for i, ai := range a {
+		h, r := TwoProduct(ai, b[i])
+		c, q = TwoSum(c, h)
+		e += q + r
+	}
+	return c + e
+}
+
+// Dot2Partials returns the running dot products of x and y.
+//
+// Element i of the result is the dot product of x[:i+1] and y[:i+1],
+// computed as with Dot2.  The result for each i is the same as that of Dot2
+// on the prefixes.  X and y are not modified.
+//
+// X and y must be of the same length, panic or nonsense results otherwise.
+func Dot2Partials(x, y []float64) []float64 {
+	d := make([]float64, len(x))
+	var p, s, q float64
+	for i, xi := range x {
+		h, r := TwoProduct(xi, y[i])
+		p, q = TwoSum(p, h)
+		s += q + r
+		d[i] = p + s
+	}
+	return d
+}
+
+// Dot3Way returns Σ a[i]*b[i]*c[i], as if computed in twice the precision
+// of a float64.
+//
+// Each triple product is formed with two chained TwoProducts, capturing the
+// errors of both multiplications.  The error of the first product is
+// multiplied by c[i] in ordinary arithmetic, contributing only a second
+// order error.  Products are accumulated as in Dot2.  Underflow is assumed
+// not to occur.
+//
+// Dot3Way panics if a, b, and c are not all of the same length.
+func Dot3Way(a, b, c []float64) float64 {
+	if len(b) != len(a) || len(c) != len(a) {
+		panic(fmt.Sprintf("slice lengths %d, %d, %d differ",
+			len(a), len(b), len(c)))
+	}
+	var s, e, q float64
+	for i, ai := range a {
+		h, r := TwoProduct(ai, b[i])
+		h1, h2 := TwoProduct(h, c[i])
+		s, q = TwoSum(s, h1)
+		e += q + (h2 + r*c[i])
+	}
+	return s + e
+}
+
+// Dot2Blocked returns a dot product of x and y, as if computed in twice the
+// precision of a float64.
+//
+// The result has the accuracy of Dot2 but elements are accumulated in four
+// independent compensated sums, interleaved, to break the serial dependency
+// of Dot2 and allow more instruction level parallelism.  The four partial
+// results are combined with TwoSum, carrying the compensation terms.
+// Whether this is faster than Dot2 depends on the processor.  The
+// error-free products are often the bottleneck rather than the dependency
+// chain; benchmark both.
+//
+// X and y must be of the same length, panic or nonsense results otherwise.
+func Dot2Blocked(x, y []float64) float64 {
+	var p0, p1, p2, p3, s0, s1, s2, s3, q0, q1, q2, q3 float64
+	n := len(x) &^ 3
+	for i := 0; i < n; i += 4 {
+		h0, r0 := TwoProduct(x[i], y[i])
+		h1, r1 := TwoProduct(x[i+1], y[i+1])
+		h2, r2 := TwoProduct(x[i+2], y[i+2])
+		h3, r3 := TwoProduct(x[i+3], y[i+3])
+		p0, q0 = TwoSum(p0, h0)
+		p1, q1 = TwoSum(p1, h1)
+		p2, q2 = TwoSum(p2, h2)
+		p3, q3 = TwoSum(p3, h3)
+		s0 += q0 + r0
+		s1 += q1 + r1
+		s2 += q2 + r2
+		s3 += q3 + r3
+	}
+	for i := n; i < len(x); i++ {
+		h, r := TwoProduct(x[i], y[i])
+		p0, q0 = TwoSum(p0, h)
+		s0 += q0 + r
+	}
+	p0, q0 = TwoSum(p0, p1)
+	p2, q2 = TwoSum(p2, p3)
+	p0, q1 = TwoSum(p0, p2)
+	return p0 + (q0 + q1 + q2 + s0 + s1 + s2 + s3)
+}
+
+// splitInt splits i into float64s h and l such that h+l exactly equals i.
+func splitInt(i int64) (h, l float64) {
+	return math.Ldexp(float64(i>>32), 32), float64(i & (1<<32 - 1))
+}
+
+// RoundDD returns the double-length value hi+lo correctly rounded to the
+// nearest float64, with ties to even.
+//
+// No special processing is needed.  IEEE 754 addition returns the correctly
+// rounded exact sum of its operands, including in the case where lo is
+// exactly half a unit in the last place of hi, so RoundDD is simply hi+lo.
+// It exists to document the result and to make intent clear at call sites.
+// Hi and lo need not be normalized.
+func RoundDD(hi, lo float64) float64 {
+	return hi + lo
+}
+
+// IntSum returns an accurate sum of values in p and whether it is an exact
+// integer total.
+//
+// Exact is true if all values in p are integers and their exact sum is in
+// the interval [-2^53, 2^53], where every integer is a float64.  Total is
+// then that exact sum.  Otherwise total is a faithful rounding of the sum,
+// as with AccSum, or if p contains an Inf or NaN, the same as that of Sum.
+// IntSum is not destructive on p.
+func IntSum(p []float64) (total float64, exact bool) {
+	exact = true
+	for _, x := range p {
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return Sum(p), false
+		}
+		if x != math.Trunc(x) {
+			exact = false
+		}
+	}
+	if exact {
+		lo, hi := SumInterval(p)
+		if lo == hi && math.Abs(lo) <= 1<<53 {
+			return lo, true
+		}
+	}
+	return AccSum(append([]float64{}, p...)), false
+}
+
+// SumSaturate returns an accurate sum of values in p, saturating at
+// ±math.MaxFloat64 rather than overflowing to ±Inf.
+//
+// Only a sum whose exact value is beyond the float64 range saturates.
+// Intermediate overflow, as from MaxFloat64 + MaxFloat64 - MaxFloat64, is
+// avoided and the finite result returned.  Otherwise the result is a
+// faithful rounding of the sum.  If p contains an Inf or NaN, the result is
+// the same as that of Sum.
+//
+// For values up to 2^960 in magnitude, the sum cannot overflow and is
+// computed with AccSum.  Note that AccSum and AccSumHuge require values in
+// this range.  Larger values are summed with ExactSumSorted.  SumSaturate is
+// not destructive on p.
+func SumSaturate(p []float64) float64 {
+	μ := 0.
+	for _, x := range p {
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return Sum(p)
+		}
+		μ = math.Max(μ, math.Abs(x))
+	}
+	if μ <= 0x1p960 {
+		return AccSum(append([]float64{}, p...))
+	}
+	s := ExactSumSorted(p)
+	if math.IsInf(s, 0) {
+		return math.Copysign(math.MaxFloat64, s)
+	}
+	return s
+}
+
+// SumBoth returns both the faithful rounding of the sum of values in p,
+// as computed by AccSum, and the nearest rounding, as computed by NearSum.
+//
+// Differ reports whether the two results differ.  It can be used to judge
+// whether the extra cost of NearSum is significant for a given distribution
+// of data.  SumBoth is not destructive on p.
+func SumBoth(p []float64) (faithful, nearest float64, differ bool) {
+	faithful = AccSum(append([]float64{}, p...))
+	nearest = NearSum(append([]float64{}, p...))
+	return faithful, nearest, faithful != nearest
+}
+
+// SumDurations returns the exact sum of the durations in d.
+//
+// Durations are accumulated in 128 bit integer arithmetic so intermediate
+// sums cannot overflow.  If the final sum is representable as a
+// time.Duration it is returned with ok true.  Otherwise the sum saturates to
+// the largest or smallest Duration and ok is false.
+func SumDurations(d []time.Duration) (sum time.Duration, ok bool) {
+	var a int128
+	for _, x := range d {
+		a.add(int64(x))
+	}
+	s, ok := a.int64()
+	return time.Duration(s), ok
+}
+
+// SumCents returns the exact sum in cents of dollar amounts in p, each
+// first rounded to the nearest cent.
+//
+// Each amount is scaled by 100 error-free with TwoProduct, so rounding is
+// of the exact value x*100, not of a rounded product.  The rounding rule is
+// round half to even.  Amounts that are exactly representable in binary,
+// such as 0.125, can be exactly half a cent; most decimal amounts, such as
+// 1.005, are not exactly representable and so are not ties.
+//
+// Cents are summed in 128 bit integer arithmetic.  If the total is
+// representable as an int64 it is returned with ok true.  Otherwise, or if
+// any amount is Inf, NaN, or too large to convert to int64 cents, ok is
+// false and the total is saturated or meaningless.
+func SumCents(p []float64) (cents int64, ok bool) {
+	var a int128
+	for _, x := range p {
+		h, r := TwoProduct(x, 100)
+		if !(math.Abs(h) < 1<<63) {
+			return 0, false
+		}
+		// Round h and r separately.  Either h is an integer or |r| < 1/4,
+		// so at most one of the fractions d and f is nonzero.
+		n := math.RoundToEven(h)
+		m := math.RoundToEven(r)
+		d := h - n
+		f := r - m
+		c := int64(m)
+		odd := (int64(n)+c)&1 != 0
+		switch {
+		case d == .5 && f > 0, d == 0 && f == .5 && odd:
+			c++
+		case d == -.5 && f < 0, d == 0 && f == -.5 && odd:
+			c--
+		}
+		a.add(int64(n))
+		a.add(c)
+	}
+	return a.int64()
+}
+
+// int128 is a 128 bit two's complement integer accumulator.
+type int128 struct {
+	hi int64
+	lo uint64
+}
+
+// add adds x to a.
+func (a *int128) add(x int64) {
+	var c uint64
+	a.lo, c = bits.Add64(a.lo, uint64(x), 0)
+	a.hi += int64(c) + x>>63
+}
+
+// int64 returns a as an int64 and true if it is representable.  Otherwise
+// it returns the int64 value of the same sign with the largest magnitude,
+// and false.
+func (a *int128) int64() (int64, bool) {
+	switch {
+	case a.hi == int64(a.lo)>>63:
+		return int64(a.lo), true
+	case a.hi < 0:
+		return math.MinInt64, false
+	}
+	return math.MaxInt64, false
+}
+
+// Summator is the signature of summation functions in this package.
+type Summator func([]float64) float64
+
+// Pick returns a summation function suitable for sums with condition number
+// cond.
+//
+// The function returned is the cheapest in the package that gives a
+// faithfully rounded sum for slices of up to about 1000 elements, with
+// the given condition number.  Thresholds are derived from the a priori
+// error bounds of the algorithms, so results are typically faithful for
+// much longer slices as well.
+//
+// For cond up to 1e9, Pick returns Sum2.  For cond up to 1e22 it returns
+// a function computing SumKVert with K = 3.  For larger cond it returns
+// AccSum, which is faithful for any condition number but is destructive on
+// its argument.
+//
+// Sum is never returned.  Its error grows with the length of the slice even
+// for well conditioned sums.
+func Pick(cond float64) Summator {
+	switch {
+	case cond <= 1e9:
+		return Sum2
+	case cond <= 1e22:
+		return sumK3
+	}
+	return AccSum
+}
+
+func sumK3(p []float64) float64 { return SumKVert(p, 3) }
+
+// UlpDiff returns the signed number of float64 values from b to a.
+//
+// The result is positive when a > b, negative when a < b, and zero when
+// a == b, including for +0 and -0.  Adjacent float64s differ by 1.
+// Infinities are treated as one past the largest finite values.  The result
+// saturates at math.MaxInt64 and math.MinInt64.  If either argument is NaN,
+// the result is math.MaxInt64.
+func UlpDiff(a, b float64) int64 {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.MaxInt64
+	}
+	oa, ob := ordinal(a), ordinal(b)
+	switch {
+	case ob < 0 && oa > math.MaxInt64+ob:
+		return math.MaxInt64
+	case ob > 0 && oa < math.MinInt64+ob:
+		return math.MinInt64
+	}
+	return oa - ob
+}
+
+// ordinal maps float64s to int64s in order, with both zeros mapping to 0.
+func ordinal(x float64) int64 {
+	i := int64(math.Float64bits(x))
+	if i < 0 {
+		i = math.MinInt64 - i
+	}
+	return i
+}
+
+// CompareSums runs the summation functions of the package on p and returns
+// the UlpDiff of each result from the correctly rounded sum.
+//
+// Map keys are function names.  Functions taking a fold count K are run
+// with K = 2 and K = 3, with keys such as "SumK(2)".  Each function is run
+// on a copy of p, so CompareSums is not destructive on p.
+func CompareSums(p []float64) map[string]int64 {
+	want := ExactSumSorted(p)
+	m := map[string]int64{}
+	for _, f := range summations {
+		m[f.name] = UlpDiff(f.f(append([]float64{}, p...)), want)
+	}
+	return m
+}
+
+// ProfileRow holds results of profiling a summation function.
+type ProfileRow struct {
+	Name      string  // function name, as with CompareSums
+	Result    float64 // sum computed by the function
+	UlpErr    int64   // absolute UlpDiff of Result from the correct sum
+	NsPerCall float64 // measured time per call, in nanoseconds
+}
+
+// Profile runs the summation functions of the package on p and returns
+// the result, error, and time of each.
+//
+// Functions are run in order of the rows returned, the same functions as
+// run by CompareSums.  Each is timed over a number of calls, on copies of p
+// made before timing starts, so times do not include copying.  Profile is
+// not destructive on p.
+func Profile(p []float64) []ProfileRow {
+	want := ExactSumSorted(p)
+	reps := 1
+	if len(p) > 0 && len(p) < 1e5 {
+		reps = 1e5 / len(p)
+	}
+	if reps > 1000 {
+		reps = 1000
+	}
+	c := make([][]float64, reps)
+	rows := make([]ProfileRow, len(summations))
+	for i, f := range summations {
+		for j := range c {
+			c[j] = append(c[j][:0], p...)
+		}
+		var r float64
+		start := time.Now()
+		for _, q := range c {
+			r = f.f(q)
+		}
+		ns := float64(time.Since(start).Nanoseconds()) / float64(reps)
+		d := UlpDiff(r, want)
+		switch {
+		case d == math.MinInt64:
+			d = math.MaxInt64
+		case d < 0:
+			d = -d
+		}
+		rows[i] = ProfileRow{f.name, r, d, ns}
+	}
+	return rows
+}
+
+var summations = []struct {
+	name string
+	f    Summator
+}{
+	{"Sum", Sum},
+	{"PairSum", PairSum},
+	{"KahanSum", KahanSum},
+	{"KahanB", KahanB},
+	{"XSum", XSum},
+	{"PriestSum", PriestSum},
+	{"Sum2", Sum2},
+	{"SumK(2)", func(p []float64) float64 { return SumK(p, 2) }},
+	{"SumK(3)", func(p []float64) float64 { return SumK(p, 3) }},
+	{"SumKVert(2)", func(p []float64) float64 { return SumKVert(p, 2) }},
+	{"SumKVert(3)", func(p []float64) float64 { return SumKVert(p, 3) }},
+	{"PrecSum(2)", func(p []float64) float64 { return PrecSum(p, 2) }},
+	{"PrecSum(3)", func(p []float64) float64 { return PrecSum(p, 3) }},
+	{"AccSum", AccSum},
+	{"AccSumHuge", AccSumHuge},
+	{"DownSum", DownSum},
+	{"UpSum", UpSum},
+	{"NearSum", NearSum},
+}
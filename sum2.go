@@ -0,0 +1,309 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum
+
+// Sum2.go:  Variations of Sum2 for data in other forms.  Each computes a sum
+// as if in twice the precision of a float64.
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// SumMask returns a sum of the values p[i] where mask[i] is true.
+//
+// The sum is computed as with Sum2, without allocating a filtered copy of p.
+// SumMask panics if p and mask differ in length.
+func SumMask(p []float64, mask []bool) float64 {
+	if len(mask) != len(p) {
+		panic(fmt.Sprintf("len(mask) = %d, want len(p) = %d",
+			len(mask), len(p)))
+	}
+	var s, e, y float64
+	for i, x := range p {
+		if mask[i] {
+			s, y = TwoSum(s, x)
+			e += y
+		}
+	}
+	return s + e
+}
+
+// GroupSum returns sums of values in p grouped by corresponding values in
+// key.
+//
+// Element p[i] is added to the sum for group key[i].  Each group sum is
+// computed as with Sum2.  GroupSum panics if p and key differ in length.
+func GroupSum[K comparable](p []float64, key []K) map[K]float64 {
+	if len(key) != len(p) {
+		panic(fmt.Sprintf("len(key) = %d, want len(p) = %d",
+			len(key), len(p)))
+	}
+	type acc struct{ s, e float64 }
+	g := map[K]acc{}
+	var y float64
+	for i, x := range p {
+		a := g[key[i]]
+		a.s, y = TwoSum(a.s, x)
+		a.e += y
+		g[key[i]] = a
+	}
+	r := make(map[K]float64, len(g))
+	for k, a := range g {
+		r[k] = a.s + a.e
+	}
+	return r
+}
+
+// SumBatch returns the sum of each slice in batches.
+//
+// Element i of the result is the sum of batches[i], the same as that of
+// Sum2(batches[i]).  Batches are independent and are summed concurrently by
+// up to runtime.GOMAXPROCS(0) goroutines.  SumBatch is not destructive on
+// batches.
+func SumBatch(batches [][]float64) []float64 {
+	r := make([]float64, len(batches))
+	w := runtime.GOMAXPROCS(0)
+	if w > len(batches) {
+		w = len(batches)
+	}
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(w)
+	for ; w > 0; w-- {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= len(batches) {
+					return
+				}
+				r[i] = Sum2(batches[i])
+			}
+		}()
+	}
+	wg.Wait()
+	return r
+}
+
+// SumVec returns a sum of the elements of v.
+//
+// V may be any type with the method set of the Vector interface of
+// gonum.org/v1/gonum/mat, so vectors need not be copied to a slice.  The sum
+// is computed as with Sum2.
+func SumVec(v interface {
+	Len() int
+	AtVec(int) float64
+}) float64 {
+	var s, e, y float64
+	for i, n := 0, v.Len(); i < n; i++ {
+		s, y = TwoSum(s, v.AtVec(i))
+		e += y
+	}
+	return s + e
+}
+
+// SumProgress returns a sum of values in p, calling cb to report progress.
+//
+// The sum is computed as with Sum2 and the result is the same.  After each
+// multiple of every values has been summed, cb is called with the number
+// summed so far, so it is called len(p)/every times in all.  SumProgress
+// panics if every is not positive.
+func SumProgress(p []float64, every int, cb func(done int)) float64 {
+	if every <= 0 {
+		panic(fmt.Sprintf("every = %d, must be positive", every))
+	}
+	var s, e, y float64
+	for i, x := range p {
+		s, y = TwoSum(s, x)
+		e += y
+		if (i+1)%every == 0 {
+			cb(i + 1)
+		}
+	}
+	return s + e
+}
+
+// SegmentedCumSum returns running sums of values in p, restarting from zero
+// at each i where resetAt[i] is true.
+//
+// Element i of the result is the sum of p[j] for j from the last reset at or
+// before i, through i.  Each running sum is computed as with Sum2, so the
+// result for each i is the same as Sum2 of the segment so far.
+// SegmentedCumSum panics if p and resetAt differ in length.
+func SegmentedCumSum(p []float64, resetAt []bool) []float64 {
+	if len(resetAt) != len(p) {
+		panic(fmt.Sprintf("len(resetAt) = %d, want len(p) = %d",
+			len(resetAt), len(p)))
+	}
+	r := make([]float64, len(p))
+	var s, e, y float64
+	for i, x := range p {
+		if resetAt[i] {
+			s, e = 0, 0
+		}
+		s, y = TwoSum(s, x)
+		e += y
+		r[i] = s + e
+	}
+	return r
+}
+
+// Sum2D returns a sum of all values in m.
+//
+// A single compensated sum is accumulated across all rows, so row
+// boundaries do not introduce additional rounding.  Rows may differ in
+// length.
+func Sum2D(m [][]float64) float64 {
+	var s, e, y float64
+	for _, row := range m {
+		for _, x := range row {
+			s, y = TwoSum(s, x)
+			e += y
+		}
+	}
+	return s + e
+}
+
+// RowSums returns a sum of each row of m.
+//
+// Rows may differ in length.
+func RowSums(m [][]float64) []float64 {
+	s := make([]float64, len(m))
+	for i, row := range m {
+		s[i] = Sum2(row)
+	}
+	return s
+}
+
+// ColSums returns a sum of each column of m.
+//
+// All rows of m must have the same length.  ColSums panics if m is ragged.
+func ColSums(m [][]float64) []float64 {
+	nc := cols(m, "m")
+	s := make([]float64, nc)
+	e := make([]float64, nc)
+	var y float64
+	for _, row := range m {
+		for j, x := range row {
+			s[j], y = TwoSum(s[j], x)
+			e[j] += y
+		}
+	}
+	for j, ej := range e {
+		s[j] += ej
+	}
+	return s
+}
+
+// SumScale returns the sum of values in p multiplied by factor.
+//
+// The sum is computed as with Sum2 but kept in double length form.  The
+// product with factor is formed with TwoProduct, so factor is applied to the
+// double length sum and the result is rounded only once.  The result is
+// more accurate than factor * Sum2(p), which rounds both the sum and the
+// product.
+func SumScale(p []float64, factor float64) float64 {
+	var s, e, y float64
+	for _, x := range p {
+		s, y = TwoSum(s, x)
+		e += y
+	}
+	s, e = TwoSum(s, e)
+	h, r := TwoProduct(s, factor)
+	return h + (r + e*factor)
+}
+
+// SumReciprocals returns a sum of reciprocals of values in d.
+//
+// The rounding error of each reciprocal r = 1/d[i] is estimated from the
+// exact residual 1 - r*d[i], formed with TwoProduct, and included in the
+// compensation, so errors of division as well as of summation are
+// corrected.
+//
+// The correction is skipped where it cannot be formed: when r is Inf or
+// NaN, as for d[i] = 0 or |d[i]| so small that 1/d[i] overflows, when d[i]
+// is Inf, and when |r| >= 2^996, where TwoProduct would overflow.  Infinite
+// reciprocals are summed separately, so the result is ±Inf rather than NaN
+// when all infinite reciprocals have the same sign.  A NaN in d gives NaN.
+func SumReciprocals(d []float64) float64 {
+	var s, e, y, inf float64
+	for _, x := range d {
+		r := 1 / x
+		if math.IsInf(r, 0) || math.IsNaN(r) {
+			inf += r
+			continue
+		}
+		c := 0.
+		if math.Abs(r) < 0x1p996 && !math.IsInf(x, 0) {
+			h, l := TwoProduct(r, x)
+			c = (1 - h - l) / x
+		}
+		s, y = TwoSum(s, r)
+		e += y + c
+	}
+	if inf != 0 { // also true for NaN
+		return inf
+	}
+	return s + e
+}
+
+// PartialSum is a compensated sum, as accumulated by Sum2, in a form that
+// can be passed between independent workers and merged.
+//
+// The value of the sum is approximately Sum+Comp.  The zero value is an
+// empty sum.
+type PartialSum struct {
+	Sum, Comp float64
+}
+
+// SumPartial returns the compensated sum of values in p as a PartialSum.
+func SumPartial(p []float64) PartialSum {
+	var s, e, y float64
+	for _, x := range p {
+		s, y = TwoSum(s, x)
+		e += y
+	}
+	return PartialSum{s, e}
+}
+
+// Merge returns the combined sum of ps and other.
+//
+// The two running sums are added with TwoSum, and the rounding error joins
+// the combined compensation term, so merging loses no more accuracy than
+// accumulating all values in a single Sum2.
+func (ps PartialSum) Merge(other PartialSum) PartialSum {
+	s, y := TwoSum(ps.Sum, other.Sum)
+	return PartialSum{s, ps.Comp + other.Comp + y}
+}
+
+// Value returns the value of the sum rounded to float64.
+func (ps PartialSum) Value() float64 {
+	return ps.Sum + ps.Comp
+}
+
+// SumClose reports whether the sums of values in a and b agree within
+// relative tolerance relTol.
+//
+// The sums are computed as with Sum2.  Their difference is computed as a
+// single compensated sum of the values of a and the negated values of b,
+// so it is not subject to cancellation in subtracting the two sums.  The
+// result is true if the magnitude of the difference is at most relTol times
+// the larger magnitude of the two sums.  In particular two zero sums agree.
+func SumClose(a, b []float64, relTol float64) bool {
+	var d, e, y float64
+	for _, x := range a {
+		d, y = TwoSum(d, x)
+		e += y
+	}
+	for _, x := range b {
+		d, y = TwoSum(d, -x)
+		e += y
+	}
+	m := math.Max(math.Abs(Sum2(a)), math.Abs(Sum2(b)))
+	return math.Abs(d+e) <= relTol*m
+}
@@ -0,0 +1,298 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum_test
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/soniakeys/accsum"
+)
+
+// randSlice returns n values of random sign and widely varying magnitude.
+func randSlice(n int) []float64 {
+	p := make([]float64, n)
+	for i := range p {
+		p[i] = math.Ldexp(rand.Float64()*2-1, rand.Intn(100)-50)
+	}
+	return p
+}
+
+func TestSumMask(t *testing.T) {
+	p := randSlice(1000)
+	mask := make([]bool, len(p))
+	var f []float64
+	for i, x := range p {
+		if rand.Intn(3) > 0 {
+			mask[i] = true
+			f = append(f, x)
+		}
+	}
+	if got, want := accsum.SumMask(p, mask), accsum.Sum2(f); got != want {
+		t.Fatalf("SumMask = %.17g, want %.17g", got, want)
+	}
+}
+
+func TestGroupSum(t *testing.T) {
+	p := randSlice(1000)
+	key := make([]string, len(p))
+	groups := map[string][]float64{}
+	for i, x := range p {
+		k := string(rune('a' + rand.Intn(5)))
+		key[i] = k
+		groups[k] = append(groups[k], x)
+	}
+	g := accsum.GroupSum(p, key)
+	if len(g) != len(groups) {
+		t.Fatalf("GroupSum returned %d groups, want %d", len(g), len(groups))
+	}
+	for k, f := range groups {
+		if got, want := g[k], accsum.Sum2(f); got != want {
+			t.Fatalf("group %s sum = %.17g, want %.17g", k, got, want)
+		}
+	}
+}
+
+// vec implements the Len and AtVec methods of a gonum vector.
+type vec []float64
+
+func (v vec) Len() int            { return len(v) }
+func (v vec) AtVec(i int) float64 { return v[i] }
+
+func batches(n, m int) [][]float64 {
+	b := make([][]float64, n)
+	for i := range b {
+		b[i], _, _ = accsum.GenSum(m, 1e10)
+	}
+	return b
+}
+
+func TestSumBatch(t *testing.T) {
+	b := batches(100, 1+rand.Intn(100))
+	b = append(b, nil, []float64{math.NaN()})
+	got := accsum.SumBatch(b)
+	if len(got) != len(b) {
+		t.Fatalf("len(SumBatch) = %d, want %d", len(got), len(b))
+	}
+	for i, p := range b {
+		if want := accsum.Sum2(p); math.Float64bits(got[i]) != math.Float64bits(want) {
+			t.Fatalf("batch %d: SumBatch = %.17g, Sum2 = %.17g", i, got[i], want)
+		}
+	}
+	if r := accsum.SumBatch(nil); len(r) != 0 {
+		t.Fatalf("SumBatch(nil) = %v", r)
+	}
+}
+
+func BenchmarkSumBatch(b *testing.B) {
+	p := batches(1000, 1000)
+	b.Run("serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, q := range p {
+				accsum.Sum2(q)
+			}
+		}
+	})
+	b.Run("SumBatch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			accsum.SumBatch(p)
+		}
+	})
+}
+
+func TestSumVec(t *testing.T) {
+	p, _, _ := accsum.GenSum(1000, 1e15)
+	if got, want := accsum.SumVec(vec(p)), accsum.Sum2(p); got != want {
+		t.Fatalf("SumVec = %.17g, want %.17g", got, want)
+	}
+}
+
+func TestSumProgress(t *testing.T) {
+	p, _, _ := accsum.GenSum(1050, 1e12)
+	var calls []int
+	got := accsum.SumProgress(p, 100, func(done int) { calls = append(calls, done) })
+	if want := accsum.Sum2(p); got != want {
+		t.Fatalf("SumProgress = %.17g, want %.17g", got, want)
+	}
+	if len(calls) != 10 {
+		t.Fatalf("callback called %d times, want 10", len(calls))
+	}
+	for i, done := range calls {
+		if done != 100*(i+1) {
+			t.Fatalf("call %d reported %d done, want %d", i, done, 100*(i+1))
+		}
+	}
+}
+
+func TestSegmentedCumSum(t *testing.T) {
+	p, _, _ := accsum.GenSum(500, 1e12)
+	reset := make([]bool, len(p))
+	for i := range reset {
+		reset[i] = rand.Intn(50) == 0
+	}
+	r := accsum.SegmentedCumSum(p, reset)
+	start := 0
+	for i := range p {
+		if reset[i] {
+			start = i
+		}
+		if want := accsum.Sum2(p[start : i+1]); r[i] != want {
+			t.Fatalf("element %d = %.17g, want %.17g", i, r[i], want)
+		}
+	}
+}
+
+func TestSum2D(t *testing.T) {
+	m := make([][]float64, 20)
+	var f []float64
+	for i := range m {
+		m[i] = randSlice(rand.Intn(50)) // ragged
+		f = append(f, m[i]...)
+	}
+	if got, want := accsum.Sum2D(m), accsum.Sum2(f); got != want {
+		t.Fatalf("Sum2D = %.17g, want %.17g", got, want)
+	}
+}
+
+func TestRowColSums(t *testing.T) {
+	m := [][]float64{
+		{1, 2, 3},
+		{1e-3, 2e-3, 3e-3},
+		{1e20, -1e20, 1e20},
+		{4e-3, 5e-3, 6e-3},
+		{-1e20, 1e20, -1e20},
+	}
+	r := accsum.RowSums(m)
+	for i, row := range m {
+		if want := accsum.Sum2(row); r[i] != want {
+			t.Fatalf("RowSums[%d] = %g, want %g", i, r[i], want)
+		}
+	}
+	c := accsum.ColSums(m)
+	for j := range m[0] {
+		col := make([]float64, len(m))
+		for i, row := range m {
+			col[i] = row[j]
+		}
+		if want := accsum.Sum2(col); c[j] != want {
+			t.Fatalf("ColSums[%d] = %g, want %g", j, c[j], want)
+		}
+		if want := accsum.ExactSumSorted(col); math.Abs(c[j]-want) > ulp(want) {
+			t.Fatalf("ColSums[%d] = %.17g, want %.17g", j, c[j], want)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("ColSums did not panic on ragged input")
+		}
+	}()
+	accsum.ColSums([][]float64{{1, 2}, {3}})
+}
+
+func TestSumScale(t *testing.T) {
+	twoStepWrong := 0
+	for i := 0; i < 1000; i++ {
+		p := make([]float64, 10)
+		for j := range p {
+			p[j] = rand.Float64()
+		}
+		factor := 1 + rand.Float64()
+		exact := new(big.Float).SetPrec(1000)
+		var bx big.Float
+		for _, x := range p {
+			exact.Add(exact, bx.SetFloat64(x))
+		}
+		want, _ := exact.Mul(exact, bx.SetFloat64(factor)).Float64()
+		if got := accsum.SumScale(p, factor); got != want {
+			t.Fatalf("SumScale = %.17g, want %.17g", got, want)
+		}
+		if factor*accsum.Sum2(p) != want {
+			twoStepWrong++
+		}
+	}
+	if twoStepWrong == 0 {
+		t.Fatal("factor * Sum2 always correct, test ineffective")
+	}
+}
+
+func TestSumReciprocals(t *testing.T) {
+	n := 100000
+	d := make([]float64, n)
+	ref := new(big.Float).SetPrec(200)
+	var r big.Float
+	r.SetPrec(200)
+	one := big.NewFloat(1)
+	for i := range d {
+		d[i] = float64(i + 1)
+		ref.Add(ref, r.Quo(one, big.NewFloat(d[i])))
+	}
+	want, _ := ref.Float64()
+	if got := accsum.SumReciprocals(d); math.Abs(got-want) > ulp(want) {
+		t.Fatalf("SumReciprocals = %.17g, want %.17g", got, want)
+	}
+	for _, tc := range []struct {
+		d    []float64
+		want float64
+	}{
+		{[]float64{2, 0}, math.Inf(1)},
+		{[]float64{2, math.Copysign(0, -1)}, math.Inf(-1)},
+		{[]float64{2, 1e-310}, math.Inf(1)},
+		{[]float64{2, -1e-310, 4}, math.Inf(-1)},
+		{[]float64{2, math.Inf(1)}, .5},
+		{[]float64{0x1p-997, 0x1p-997}, 0x1p998},
+		{[]float64{0, math.Copysign(0, -1)}, math.NaN()},
+	} {
+		got := accsum.SumReciprocals(tc.d)
+		if got != tc.want && !(math.IsNaN(got) && math.IsNaN(tc.want)) {
+			t.Errorf("SumReciprocals(%g) = %g, want %g", tc.d, got, tc.want)
+		}
+	}
+}
+
+func TestPartialSum(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		p, _, _ := accsum.GenSum(1000, 1e6)
+		want := accsum.ExactSumSorted(p)
+		var ps accsum.PartialSum
+		for q := p; len(q) > 0; {
+			n := rand.Intn(len(q) + 1)
+			ps = ps.Merge(accsum.SumPartial(q[:n]))
+			q = q[n:]
+		}
+		// both faithful for this condition number
+		got := ps.Value()
+		if math.Abs(got-want) > ulp(want) {
+			t.Fatalf("merged PartialSum = %.17g, want %.17g", got, want)
+		}
+		if s2 := accsum.Sum2(p); math.Abs(got-s2) > 2*ulp(want) {
+			t.Fatalf("merged PartialSum = %.17g, Sum2 = %.17g", got, s2)
+		}
+	}
+}
+
+func TestSumClose(t *testing.T) {
+	p, _, _ := accsum.GenSum(100, 1e10)
+	q := append([]float64{}, p...)
+	rand.Shuffle(len(q), func(i, j int) { q[i], q[j] = q[j], q[i] })
+	s := accsum.ExactSumSorted(p)
+	for _, c := range []struct {
+		a, b   []float64
+		relTol float64
+		want   bool
+	}{
+		{p, q, 1e-15, true},
+		{p, append(q, 16*ulp(s)), 1e-15, false},
+		{p, append(q, 16*ulp(s)), 1e-14, true},
+		{[]float64{1}, []float64{2}, .1, false},
+		{[]float64{1e20, -1e20}, []float64{0, -0.}, 0, true},
+		{nil, nil, 0, true},
+	} {
+		if got := accsum.SumClose(c.a, c.b, c.relTol); got != c.want {
+			t.Fatalf("SumClose(len %d, len %d, %g) = %t, want %t",
+				len(c.a), len(c.b), c.relTol, got, c.want)
+		}
+	}
+}
@@ -5,6 +5,13 @@ package accsum_test
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
 
 	"github.com/soniakeys/accsum"
 )
@@ -130,3 +137,709 @@ func ExampleXSum() {
 	// XSum:     1.0000000000147541e+20
 	// Triangle:             1475412681
 }
+
+func TestCosSum(t *testing.T) {
+	// slowly converging series, cos(kθ)/(k+1)
+	n := 100000
+	θ := .001
+	a := make([]float64, n)
+	ref := new(big.Float).SetPrec(2000)
+	var tm, tp big.Float
+	tm.SetPrec(2000)
+	for k := range a {
+		a[k] = 1 / float64(k+1)
+		tm.Mul(tp.SetFloat64(a[k]), big.NewFloat(math.Cos(float64(k)*θ)))
+		ref.Add(ref, &tm)
+	}
+	want, _ := ref.Float64()
+	got := accsum.CosSum(a, θ)
+	if math.Abs(got-want) > math.Abs(math.Nextafter(want, 0)-want) {
+		t.Fatalf("CosSum = %.17g, want %.17g", got, want)
+	}
+}
+
+func TestSumDiag(t *testing.T) {
+	s, b := accsum.SumDiag([]float64{1, 2, 3, 4})
+	if s != 10 || b != 0 {
+		t.Fatalf("SumDiag = %g, %d, want 10, 0", s, b)
+	}
+	s, b = accsum.SumDiag([]float64{1e20, 17, -1e20})
+	if s != 17 || b != accsum.P {
+		t.Fatalf("SumDiag = %g, %d, want 17, %d", s, b, accsum.P)
+	}
+	p, want, c := accsum.GenSum(100, 1e10)
+	s, b = accsum.SumDiag(p)
+	if math.Abs(s-want) > ulp(want) {
+		t.Fatalf("SumDiag = %.17g, want %.17g", s, want)
+	}
+	if lb := int(math.Log2(c)); b < lb-1 || b > lb+1 {
+		t.Fatalf("SumDiag lost bits = %d for condition %g, want about %d",
+			b, c, lb)
+	}
+}
+
+func TestDotInt(t *testing.T) {
+	x := []int64{1<<62 + 1, -1 << 62, math.MaxInt64, 12345}
+	y := []int64{3, 3, -1<<40 - 7, math.MinInt64}
+	var want, p big.Int
+	for i, xi := range x {
+		want.Add(&want, p.Mul(big.NewInt(xi), big.NewInt(y[i])))
+	}
+	w, _ := new(big.Float).SetInt(&want).Float64()
+	if got := accsum.DotInt(x, y); got != w {
+		t.Fatalf("DotInt = %.17g, want %.17g", got, w)
+	}
+	// naive conversion loses the 1 in 1<<62 + 1
+	if got := accsum.DotInt(x[:2], y[:2]); got != 3 {
+		t.Fatalf("DotInt = %g, want 3", got)
+	}
+}
+
+func TestPick(t *testing.T) {
+	ptr := func(f accsum.Summator) uintptr { return reflect.ValueOf(f).Pointer() }
+	sum2 := ptr(accsum.Sum2)
+	accSum := ptr(accsum.AccSum)
+	var last uintptr
+	strength := 0
+	for _, c := range []float64{1, 1e5, 1e9, 1e12, 1e20, 1e25, 1e40} {
+		f := accsum.Pick(c)
+		pf := ptr(f)
+		if pf != last {
+			strength++
+			last = pf
+		}
+		switch {
+		case pf == sum2 && strength != 1,
+			pf == accSum && strength != 3,
+			pf != sum2 && pf != accSum && strength != 2:
+			t.Fatalf("Pick(%g) out of order", c)
+		}
+		p, s, _ := accsum.GenSum(100, c)
+		if got := f(p); math.Abs(got-s) > ulp(s) {
+			t.Fatalf("Pick(%g) gives %.17g, want %.17g", c, got, s)
+		}
+	}
+	for _, c := range []float64{1, 1e10, 1e25} {
+		f := accsum.Pick(c)
+		if s := f(nil); s != 0 {
+			t.Fatalf("Pick(%g)(nil) = %g, want 0", c, s)
+		}
+		if s := f([]float64{3}); s != 3 {
+			t.Fatalf("Pick(%g)([3]) = %g, want 3", c, s)
+		}
+	}
+	if strength != 3 {
+		t.Fatalf("Pick returned %d algorithms, want 3", strength)
+	}
+}
+
+func TestKahanState(t *testing.T) {
+	p, _, _ := accsum.GenSum(1000, 1e8)
+	want := accsum.KahanSum(p)
+	for i := 0; i < 100; i++ {
+		var s, c float64
+		for q := p; len(q) > 0; {
+			n := rand.Intn(len(q) + 1)
+			s, c = accsum.KahanState(q[:n], s, c)
+			q = q[n:]
+		}
+		if s != want {
+			t.Fatalf("KahanState sum = %.17g, want %.17g", s, want)
+		}
+	}
+}
+
+func TestUlpDiff(t *testing.T) {
+	nz := math.Copysign(0, -1)
+	for _, tc := range []struct {
+		a, b float64
+		d    int64
+	}{
+		{1, 1, 0},
+		{0, nz, 0},
+		{math.Nextafter(1, 2), 1, 1},
+		{1, math.Nextafter(1, 2), -1},
+		{math.SmallestNonzeroFloat64, -math.SmallestNonzeroFloat64, 2},
+		{math.SmallestNonzeroFloat64, nz, 1},
+		{2, 1, 1 << 52},
+		{math.Inf(1), math.MaxFloat64, 1},
+		{math.MaxFloat64, -math.MaxFloat64, math.MaxInt64},
+		{-math.MaxFloat64, math.MaxFloat64, math.MinInt64},
+		{math.NaN(), 1, math.MaxInt64},
+	} {
+		if d := accsum.UlpDiff(tc.a, tc.b); d != tc.d {
+			t.Fatalf("UlpDiff(%g, %g) = %d, want %d", tc.a, tc.b, d, tc.d)
+		}
+	}
+}
+
+func TestCompareSums(t *testing.T) {
+	p, _, _ := accsum.GenSum(1000, 1e25)
+	c := accsum.CompareSums(p)
+	for _, name := range []string{"NearSum", "AccSum", "AccSumHuge",
+		"DownSum", "UpSum", "PrecSum(3)"} {
+		d, ok := c[name]
+		if !ok {
+			t.Fatalf("no result for %s", name)
+		}
+		if d < -1 || d > 1 {
+			t.Fatalf("%s ulp difference %d, want faithful", name, d)
+		}
+	}
+	if d := c["NearSum"]; d != 0 {
+		t.Fatalf("NearSum ulp difference %d, want 0", d)
+	}
+	if d := c["Sum"]; d > -1e6 && d < 1e6 {
+		t.Fatalf("Sum ulp difference %d, want large for ill-conditioned sum", d)
+	}
+	for _, p := range [][]float64{nil, {1}, {1, -3}} {
+		for name, d := range accsum.CompareSums(p) {
+			if d != 0 {
+				t.Fatalf("CompareSums(%g) %s ulp difference %d, want 0", p, name, d)
+			}
+		}
+	}
+	for _, K := range []int{-1, 0, 1, 2, 3} {
+		if s := accsum.SumKVert([]float64{1}, K); s != 1 {
+			t.Fatalf("SumKVert([1], %d) = %g, want 1", K, s)
+		}
+		if s := accsum.SumKVert(nil, K); s != 0 {
+			t.Fatalf("SumKVert(nil, %d) = %g, want 0", K, s)
+		}
+	}
+}
+
+func TestDotMixed(t *testing.T) {
+	x64, y, _, _ := accsum.GenDot(100, 1e12)
+	x := make([]float32, len(x64))
+	for i, xi := range x64 {
+		x[i] = float32(xi)
+		x64[i] = float64(x[i])
+	}
+	if got, want := accsum.DotMixed(x, y), accsum.Dot2(x64, y); got != want {
+		t.Fatalf("DotMixed = %.17g, want %.17g", got, want)
+	}
+}
+
+func TestDotPerm(t *testing.T) {
+	x, y, _, _ := accsum.GenDot(128, 1e15)
+	perm := make([]int, len(x))
+	for i := range perm {
+		perm[i] = int(bits.Reverse8(uint8(i)) >> 1)
+	}
+	gx := make([]float64, len(perm))
+	gy := make([]float64, len(perm))
+	for i, j := range perm {
+		gx[i], gy[i] = x[j], y[j]
+	}
+	if got, want := accsum.DotPerm(x, y, perm), accsum.Dot2(gx, gy); got != want {
+		t.Fatalf("DotPerm = %.17g, want %.17g", got, want)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("DotPerm did not panic on out of range index")
+		}
+	}()
+	accsum.DotPerm(x, y, []int{0, len(x)})
+}
+
+func TestRoundDD(t *testing.T) {
+	u := math.Ldexp(1, -53) // half an ulp of 1
+	cases := [][2]float64{
+		{1, u},
+		{1 + 2*u, u},
+		{1, -u / 2},
+		{1 + 2*u, -u},
+		{1, u + math.Ldexp(1, -106)},
+		{-1, -u},
+		{math.MaxFloat64, math.Ldexp(1, 970)},
+		{math.MaxFloat64, math.Ldexp(1, 969)},
+	}
+	for i := 0; i < 1000; i++ {
+		h := math.Ldexp(rand.Float64(), rand.Intn(200)-100)
+		cases = append(cases, [2]float64{h, math.Ldexp(h, -53-rand.Intn(3))})
+	}
+	var a, b, s big.Float
+	s.SetPrec(2200)
+	for _, c := range cases {
+		s.Add(a.SetFloat64(c[0]), b.SetFloat64(c[1]))
+		want, _ := s.Float64()
+		if got := accsum.RoundDD(c[0], c[1]); got != want {
+			t.Fatalf("RoundDD(%g, %g) = %.17g, want %.17g", c[0], c[1], got, want)
+		}
+	}
+}
+
+func TestIntSum(t *testing.T) {
+	// the triangle number example of ExampleSum2, with 1e20 cancelled
+	n := 54321
+	p := make([]float64, n+1)
+	for i := range p {
+		p[i] = float64(i)
+	}
+	p[0] = 1e20
+	p = append(p, -1e20)
+	if s, exact := accsum.IntSum(p); s != float64(n*(n+1)/2) || !exact {
+		t.Fatalf("IntSum = %.17g, %t, want %d, true", s, exact, n*(n+1)/2)
+	}
+	for _, p := range [][]float64{
+		{1 << 53, 1},  // not representable
+		{1e20, 1},     // out of range
+		{1.5, 1.5},    // not integral
+		{math.Inf(1)}, // not finite
+	} {
+		if s, exact := accsum.IntSum(p); exact {
+			t.Fatalf("IntSum(%v) = %.17g, true, want exact false", p, s)
+		}
+	}
+	if s, exact := accsum.IntSum([]float64{1 << 53, -1, 1}); s != 1<<53 || !exact {
+		t.Fatalf("IntSum = %.17g, %t, want 2^53, true", s, exact)
+	}
+}
+
+func TestSumSaturate(t *testing.T) {
+	m := math.MaxFloat64
+	for _, c := range []struct {
+		p    []float64
+		want float64
+	}{
+		{[]float64{m, m}, m},                  // true overflow
+		{[]float64{-m, -m / 2}, -m},           // true overflow
+		{[]float64{m, m, -m}, m},              // avoidable overflow
+		{[]float64{-m, -m, m, m / 2}, -m / 2}, // avoidable overflow
+		{[]float64{m, 1, -m}, 1},
+		{[]float64{1, 2, 3}, 6},
+		{[]float64{1, math.Inf(1)}, math.Inf(1)},
+	} {
+		if got := accsum.SumSaturate(c.p); got != c.want {
+			t.Fatalf("SumSaturate(%v) = %g, want %g", c.p, got, c.want)
+		}
+	}
+}
+
+func TestSumBoth(t *testing.T) {
+	// found by searching results of GenSum(20, 1e25)
+	p := []float64{3.2631417108671857e+11, -9.473367231869863,
+		5.449562348015046e-11, 3.9384085672197056e-14, -0.3229615772560742,
+		-3.112317635589447e+08, 1.3249330226953374e+06,
+		5.0878337282781836e-08, 167.4932841402882, -7.509542637263912e+08,
+		658.2937687855641, 1.9161579835002347e-05, 0.5885993240350256,
+		-2.567349566052235e-17, 8.839492119224535e+24, -8.83949211922486e+24,
+		-3.979879055840998e-17, 5.118087744869716e-16, 9.046186332318401e+07,
+		-3.785765606265128e-15}
+	f, n, d := accsum.SumBoth(p)
+	if want := accsum.ExactSumSorted(p); n != want || f == n || !d {
+		t.Fatalf("SumBoth = %.17g, %.17g, %t, want nearest %.17g, differing",
+			f, n, d, want)
+	}
+	if math.Abs(f-n) > ulp(n) {
+		t.Fatalf("faithful result %.17g not adjacent to %.17g", f, n)
+	}
+	if f, n, d := accsum.SumBoth([]float64{1e20, 1, -1e20}); f != 1 || n != 1 || d {
+		t.Fatalf("SumBoth = %g, %g, %t, want 1, 1, false", f, n, d)
+	}
+}
+
+func TestSumDurations(t *testing.T) {
+	d := []time.Duration{time.Second, 250 * time.Millisecond, -time.Nanosecond}
+	if s, ok := accsum.SumDurations(d); !ok || s != 1250*time.Millisecond-1 {
+		t.Fatalf("SumDurations(%v) = %v, %t", d, s, ok)
+	}
+	const max = time.Duration(math.MaxInt64)
+	// intermediate sum overflows but the final sum is in range
+	d = []time.Duration{max, max, -max, 1, -1}
+	if s, ok := accsum.SumDurations(d); !ok || s != max {
+		t.Fatalf("SumDurations(%v) = %v, %t", d, s, ok)
+	}
+	d = []time.Duration{max, 1}
+	if s, ok := accsum.SumDurations(d); ok || s != max {
+		t.Fatalf("SumDurations(%v) = %v, %t, want saturated", d, s, ok)
+	}
+	d = []time.Duration{-max, -2}
+	if s, ok := accsum.SumDurations(d); ok || s != math.MinInt64 {
+		t.Fatalf("SumDurations(%v) = %v, %t, want saturated", d, s, ok)
+	}
+	d = []time.Duration{-max, -1}
+	if s, ok := accsum.SumDurations(d); !ok || s != math.MinInt64 {
+		t.Fatalf("SumDurations(%v) = %v, %t", d, s, ok)
+	}
+}
+
+func TestFMASum(t *testing.T) {
+	var eFused, eSep float64
+	for i := 0; i < 50; i++ {
+		a, b, _, _ := accsum.GenDot(50, 1e8)
+		c := -accsum.Dot2(a, b) * (1 + rand.Float64()*1e-10)
+		want := exactDot(append(a, c), append(b, 1))
+		if got, want := accsum.FMASum(a, b, 0), accsum.Dot2(a, b); got != want {
+			t.Fatalf("FMASum with c = 0 is %.17g, want %.17g", got, want)
+		}
+		got := accsum.FMASum(a, b, c)
+		eFused += math.Abs(got - want)
+		eSep += math.Abs(c + accsum.Dot2(a, b) - want)
+	}
+	if eFused > eSep {
+		t.Fatalf("FMASum total error %g, c + Dot2 %g", eFused, eSep)
+	}
+}
+
+func TestDot2Partials(t *testing.T) {
+	x, y, _, _ := accsum.GenDot(100, 1e20)
+	d := accsum.Dot2Partials(x, y)
+	for i := range x {
+		if want := accsum.Dot2(x[:i+1], y[:i+1]); d[i] != want {
+			t.Fatalf("partial %d = %.17g, want %.17g", i, d[i], want)
+		}
+	}
+}
+
+func TestDot3Way(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		a := randSlice(1000)
+		b := randSlice(1000)
+		c := randSlice(1000)
+		s := new(big.Float).SetPrec(2200)
+		var p, f big.Float
+		for j := range a {
+			p.SetPrec(3 * accsum.P).SetFloat64(a[j])
+			p.Mul(&p, f.SetFloat64(b[j])).Mul(&p, f.SetFloat64(c[j]))
+			s.Add(s, &p)
+		}
+		want, _ := s.Float64()
+		if got := accsum.Dot3Way(a, b, c); math.Abs(got-want) > ulp(want) {
+			t.Fatalf("Dot3Way = %.17g, want %.17g", got, want)
+		}
+	}
+}
+
+func TestDot2Blocked(t *testing.T) {
+	var eB, e2 float64
+	for _, c := range []float64{1e5, 1e8, 1e10} {
+		for i := 0; i < 10; i++ {
+			x, y, _, _ := accsum.GenDot(203, c)
+			want := exactDot(x, y)
+			got := accsum.Dot2Blocked(x, y)
+			if math.Abs(got-want) > ulp(want) {
+				t.Fatalf("cond %g: Dot2Blocked = %.17g, want %.17g", c, got, want)
+			}
+			eB += math.Abs(got-want) / ulp(want)
+			e2 += math.Abs(accsum.Dot2(x, y)-want) / ulp(want)
+		}
+	}
+	if eB > e2+1 {
+		t.Fatalf("Dot2Blocked total error %g ulps, Dot2 %g", eB, e2)
+	}
+}
+
+func TestKahanDot(t *testing.T) {
+	var eDot, eKahan, eDot2 float64
+	for i := 0; i < 20; i++ {
+		x := randSlice(10000)
+		y := randSlice(10000)
+		want := bigDot(x, y)
+		eDot += math.Abs(accsum.Dot(x, y) - want)
+		eKahan += math.Abs(accsum.KahanDot(x, y) - want)
+		eDot2 += math.Abs(accsum.Dot2(x, y) - want)
+	}
+	if !(eDot2 <= eKahan && eKahan <= eDot) {
+		t.Fatalf("total errors Dot %g, KahanDot %g, Dot2 %g", eDot, eKahan, eDot2)
+	}
+}
+
+func TestSumCents(t *testing.T) {
+	p := []float64{1e14, .01, .01, .01, -1e14}
+	naive := int64(math.Round(accsum.Sum(p) * 100))
+	if c, ok := accsum.SumCents(p); c != 3 || !ok || naive == 3 {
+		t.Fatalf("SumCents = %d, %t, naive %d, want 3, true", c, ok, naive)
+	}
+	for _, c := range []struct {
+		x    float64
+		want int64
+	}{
+		{.125, 12}, // exact ties round to even
+		{.375, 38},
+		{-.125, -12},
+		{1.005, 100}, // slightly less than 1.005
+		{2.675, 267},
+		{1e15 + .25, 1e17 + 25},
+	} {
+		if got, ok := accsum.SumCents([]float64{c.x}); got != c.want || !ok {
+			t.Fatalf("SumCents(%g) = %d, %t, want %d", c.x, got, ok, c.want)
+		}
+	}
+	if _, ok := accsum.SumCents([]float64{9e16, 9e16}); ok {
+		t.Fatal("SumCents did not report overflow")
+	}
+	if _, ok := accsum.SumCents([]float64{math.NaN()}); ok {
+		t.Fatal("SumCents accepted NaN")
+	}
+}
+
+func TestProfile(t *testing.T) {
+	p, _, _ := accsum.GenSum(1000, 1e20)
+	rows := accsum.Profile(p)
+	if len(rows) == 0 {
+		t.Fatal("no rows")
+	}
+	c := accsum.CompareSums(p)
+	for _, r := range rows {
+		if r.Name == "" || r.UlpErr < 0 || r.NsPerCall < 0 {
+			t.Fatalf("invalid row %+v", r)
+		}
+		d, ok := c[r.Name]
+		if !ok {
+			t.Fatalf("row %s not in CompareSums", r.Name)
+		}
+		switch {
+		case d == math.MinInt64:
+			d = math.MaxInt64
+		case d < 0:
+			d = -d
+		}
+		if r.UlpErr != d {
+			t.Fatalf("%s UlpErr %d, CompareSums %d", r.Name, r.UlpErr, d)
+		}
+	}
+	if len(rows) != len(c) {
+		t.Fatalf("%d rows, CompareSums has %d results", len(rows), len(c))
+	}
+	for _, p := range [][]float64{nil, {2}, {2, -.5}} {
+		want := accsum.ExactSumSorted(p)
+		for _, r := range accsum.Profile(p) {
+			if r.Result != want || r.UlpErr != 0 {
+				t.Fatalf("Profile(%g) row %+v, want result %g", p, r, want)
+			}
+		}
+	}
+}
+
+func TestAuditSum(t *testing.T) {
+	p, _, _ := accsum.GenSum(500, 1e30)
+	q := append([]float64{}, p...)
+	want := accsum.ExactSumSorted(p)
+	for i := 0; i < 100; i++ {
+		rand.Shuffle(len(q), func(i, j int) { q[i], q[j] = q[j], q[i] })
+		got := accsum.AuditSum(q)
+		if math.Float64bits(got) != math.Float64bits(want) {
+			t.Fatalf("AuditSum = %.17g, want %.17g", got, want)
+		}
+	}
+	// ties in magnitude: x and -x, +0 and -0
+	q = []float64{3, -3, 0, math.Copysign(0, -1), 1e-20, -1e-20, 0x1p-60, 3}
+	want = accsum.AuditSum(q)
+	for i := 0; i < 100; i++ {
+		rand.Shuffle(len(q), func(i, j int) { q[i], q[j] = q[j], q[i] })
+		if got := accsum.AuditSum(q); math.Float64bits(got) != math.Float64bits(want) {
+			t.Fatalf("AuditSum = %.17g, want %.17g", got, want)
+		}
+	}
+	n1 := math.Float64frombits(0x7ff8000000000002)
+	n2 := math.Float64frombits(0x7ff8000000000001)
+	for _, p := range [][]float64{{1, n1, n2}, {n2, 1, n1}} {
+		if b := math.Float64bits(accsum.AuditSum(p)); b != 0x7ff8000000000001 {
+			t.Fatalf("AuditSum with NaNs = %#x, want 0x7ff8000000000001", b)
+		}
+	}
+}
+
+func TestCondInPlace(t *testing.T) {
+	p, _, _ := accsum.GenSum(1000, 1e25)
+	scratch := make([]float64, len(p))
+	want := accsum.CondSum(accsum.AccSum, p)
+	var got float64
+	allocs := testing.AllocsPerRun(10, func() {
+		got = accsum.CondInPlace(p, scratch)
+	})
+	if got != want {
+		t.Fatalf("CondInPlace = %g, want %g", got, want)
+	}
+	if allocs != 0 {
+		t.Fatalf("CondInPlace allocated %g times, want 0", allocs)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("CondInPlace with short scratch did not panic")
+		}
+	}()
+	accsum.CondInPlace([]float64{1, 2, 3}, make([]float64, 1, 10))
+}
+
+func TestPairKahanSum(t *testing.T) {
+	var ePK, eKB float64
+	for i := 0; i < 20; i++ {
+		p, s, _ := accsum.GenSum(1000, 1e20)
+		ePK += math.Abs(accsum.PairKahanSum(p) - s)
+		eKB += math.Abs(accsum.KahanB(p) - s)
+	}
+	if ePK > eKB {
+		t.Fatalf("PairKahanSum total error %g, KahanB %g", ePK, eKB)
+	}
+	p := []float64{1, 2, 3, 4}
+	if s := accsum.PairKahanSum(p); s != 10 {
+		t.Fatalf("PairKahanSum(%v) = %g, want 10", p, s)
+	}
+}
+
+func TestTreeSum(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		p, _, _ := accsum.GenSum(1000, 1e8)
+		want := accsum.ExactSumSorted(p)
+		for _, r := range []int{2, 3, 4, 8, 16, 1000} {
+			if got := accsum.TreeSum(p, r); math.Abs(got-want) > ulp(want) {
+				t.Fatalf("radix %d: TreeSum = %.17g, want %.17g", r, got, want)
+			}
+		}
+	}
+	if s := accsum.TreeSum(nil, 2); s != 0 {
+		t.Fatalf("TreeSum(nil) = %g, want 0", s)
+	}
+}
+
+func benchSlice() []float64 {
+	return randSlice(100000)
+}
+
+func BenchmarkPairKahanSum(b *testing.B) {
+	p := benchSlice()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		accsum.PairKahanSum(p)
+	}
+}
+
+func BenchmarkPairSum(b *testing.B) {
+	p := benchSlice()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		accsum.PairSum(p)
+	}
+}
+
+func BenchmarkKahanB(b *testing.B) {
+	p := benchSlice()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		accsum.KahanB(p)
+	}
+}
+
+func BenchmarkTreeSum(b *testing.B) {
+	p := benchSlice()
+	for _, r := range []int{2, 4, 8, 16} {
+		b.Run(fmt.Sprint("radix", r), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				accsum.TreeSum(p, r)
+			}
+		})
+	}
+}
+
+func BenchmarkDot2Blocked(b *testing.B) {
+	x := benchSlice()
+	y := benchSlice()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		accsum.Dot2Blocked(x, y)
+	}
+}
+
+func BenchmarkDot2(b *testing.B) {
+	x := benchSlice()
+	y := benchSlice()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		accsum.Dot2(x, y)
+	}
+}
+
+func BenchmarkDot2Fast(b *testing.B) {
+	x := benchSlice()
+	y := benchSlice()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		accsum.Dot2Fast(x, y)
+	}
+}
+
+// wellCondSlice returns a benchSlice with all values made positive, so that
+// dot products of two such slices have condition number 1.
+func wellCondSlice() []float64 {
+	p := benchSlice()
+	for i, x := range p {
+		p[i] = math.Abs(x)
+	}
+	return p
+}
+
+func BenchmarkDot2WellCond(b *testing.B) {
+	x := wellCondSlice()
+	y := wellCondSlice()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		accsum.Dot2(x, y)
+	}
+}
+
+func BenchmarkDot2FastWellCond(b *testing.B) {
+	x := wellCondSlice()
+	y := wellCondSlice()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		accsum.Dot2Fast(x, y)
+	}
+}
+
+func BenchmarkKahanDot(b *testing.B) {
+	x := benchSlice()
+	y := benchSlice()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		accsum.KahanDot(x, y)
+	}
+}
+
+func BenchmarkDot(b *testing.B) {
+	x := benchSlice()
+	y := benchSlice()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		accsum.Dot(x, y)
+	}
+}
+
+// BenchmarkSumAlgorithms benchmarks summation functions on GenSum data over
+// a range of condition numbers.  Every function is timed on a fresh copy of
+// the data, as several are destructive, so times include a copy of 1000
+// float64s.
+func BenchmarkSumAlgorithms(b *testing.B) {
+	funcs := []struct {
+		name string
+		f    accsum.Summator
+	}{
+		{"Sum", accsum.Sum},
+		{"PairSum", accsum.PairSum},
+		{"KahanSum", accsum.KahanSum},
+		{"KahanB", accsum.KahanB},
+		{"XSum", accsum.XSum},
+		{"PriestSum", accsum.PriestSum},
+		{"Sum2", accsum.Sum2},
+		{"SumK3", func(p []float64) float64 { return accsum.SumK(p, 3) }},
+		{"SumKVert3", func(p []float64) float64 { return accsum.SumKVert(p, 3) }},
+		{"PrecSum2", func(p []float64) float64 { return accsum.PrecSum(p, 2) }},
+		{"AccSum", accsum.AccSum},
+		{"AccSumHuge", accsum.AccSumHuge},
+		{"NearSum", accsum.NearSum},
+	}
+	for _, c := range []float64{1e2, 1e10, 1e20, 1e30} {
+		p, _, _ := accsum.GenSum(1000, c)
+		q := make([]float64, len(p))
+		for _, f := range funcs {
+			b.Run(fmt.Sprintf("cond%.0e/%s", c, f.name), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					copy(q, p)
+					f.f(q)
+				}
+			})
+		}
+	}
+}
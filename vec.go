@@ -0,0 +1,76 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum
+
+// Vec.go:  Element-wise operations on slices.
+
+import "fmt"
+
+// TwoSumSlice computes error-free sums of corresponding elements of a and b.
+//
+// For each i, x[i], y[i] are set as by TwoSum(a[i], b[i]).  TwoSumSlice
+// panics if b, x, or y differ in length from a.
+func TwoSumSlice(a, b, x, y []float64) {
+	checkLen4(a, b, x, y)
+	for i, ai := range a {
+		x[i], y[i] = TwoSum(ai, b[i])
+	}
+}
+
+// TwoProductSlice computes error-free products of corresponding elements of
+// a and b.
+//
+// For each i, x[i], y[i] are set as by TwoProduct(a[i], b[i]).
+// TwoProductSlice panics if b, x, or y differ in length from a.
+func TwoProductSlice(a, b, x, y []float64) {
+	checkLen4(a, b, x, y)
+	for i, ai := range a {
+		x[i], y[i] = TwoProduct(ai, b[i])
+	}
+}
+
+// AddVec returns error-free sums of corresponding elements of a and b.
+//
+// For each i, sum[i] is the floating point sum a[i]+b[i] and sum[i]+err[i]
+// exactly equals the sum of a[i] and b[i], as by TwoSum.  AddVec allocates
+// the result slices.  It panics if a and b differ in length.
+func AddVec(a, b []float64) (sum, err []float64) {
+	if len(b) != len(a) {
+		panic(fmt.Sprintf("len(b) = %d, want len(a) = %d", len(b), len(a)))
+	}
+	sum = make([]float64, len(a))
+	err = make([]float64, len(a))
+	TwoSumSlice(a, b, sum, err)
+	return
+}
+
+// Axpy2 returns alpha*x + y, computed element-wise and correctly rounded.
+//
+// Each product alpha*x[i] is formed error-free with TwoProduct and the
+// three terms of the exact result are summed with NearSum, so each element
+// of the result is alpha*x[i]+y[i] rounded to nearest as if by a fused
+// multiply-add, even when y[i] nearly cancels the product.  Products must
+// not underflow or overflow.
+//
+// Axpy2 allocates the result.  It panics if x and y differ in length.
+func Axpy2(alpha float64, x, y []float64) []float64 {
+	if len(y) != len(x) {
+		panic(fmt.Sprintf("len(y) = %d, want len(x) = %d", len(y), len(x)))
+	}
+	r := make([]float64, len(x))
+	var t [3]float64
+	for i, xi := range x {
+		h, l := TwoProduct(alpha, xi)
+		t = [3]float64{h, l, y[i]}
+		r[i] = NearSum(t[:])
+	}
+	return r
+}
+
+func checkLen4(a, b, x, y []float64) {
+	if len(b) != len(a) || len(x) != len(a) || len(y) != len(a) {
+		panic(fmt.Sprintf("slice lengths %d, %d, %d, %d differ",
+			len(a), len(b), len(x), len(y)))
+	}
+}
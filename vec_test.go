@@ -0,0 +1,128 @@
+// Copyright 2014 Sonia Keys
+// License MIT: http://opensource.org/licenses/MIT
+
+package accsum_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/soniakeys/accsum"
+)
+
+func TestTwoSumSlice(t *testing.T) {
+	a := randSlice(1000)
+	b := randSlice(1000)
+	x := make([]float64, len(a))
+	y := make([]float64, len(a))
+	accsum.TwoSumSlice(a, b, x, y)
+	for i, ai := range a {
+		if xi, yi := accsum.TwoSum(ai, b[i]); x[i] != xi || y[i] != yi {
+			t.Fatalf("TwoSumSlice element %d = %g, %g, want %g, %g",
+				i, x[i], y[i], xi, yi)
+		}
+	}
+}
+
+func TestTwoProductSlice(t *testing.T) {
+	a := randSlice(1000)
+	b := randSlice(1000)
+	x := make([]float64, len(a))
+	y := make([]float64, len(a))
+	accsum.TwoProductSlice(a, b, x, y)
+	for i, ai := range a {
+		if xi, yi := accsum.TwoProduct(ai, b[i]); x[i] != xi || y[i] != yi {
+			t.Fatalf("TwoProductSlice element %d = %g, %g, want %g, %g",
+				i, x[i], y[i], xi, yi)
+		}
+	}
+}
+
+func TestAddVec(t *testing.T) {
+	a := randSlice(1000)
+	b := randSlice(1000)
+	sum, err := accsum.AddVec(a, b)
+	var x, y, s, e big.Float
+	for i, ai := range a {
+		x.SetFloat64(ai)
+		y.SetFloat64(b[i])
+		s.SetFloat64(sum[i])
+		e.SetFloat64(err[i])
+		x.SetPrec(200).Add(&x, &y)
+		s.SetPrec(200).Add(&s, &e)
+		if sum[i] != ai+b[i] || x.Cmp(&s) != 0 {
+			t.Fatalf("AddVec element %d = %g, %g, not exact sum of %g, %g",
+				i, sum[i], err[i], ai, b[i])
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("AddVec did not panic on length mismatch")
+		}
+	}()
+	accsum.AddVec(a, b[1:])
+}
+
+func TestAxpy2(t *testing.T) {
+	alpha := 1e15 + 1
+	x := randSlice(1000)
+	y := make([]float64, len(x))
+	for i, xi := range x {
+		// nearly cancel the product
+		y[i] = -alpha * xi * (1 + float64(i%7-3)*1e-16)
+	}
+	r := accsum.Axpy2(alpha, x, y)
+	var a, bx, by big.Float
+	a.SetFloat64(alpha)
+	for i, xi := range x {
+		bx.SetPrec(200).SetFloat64(xi)
+		bx.Mul(&bx, &a).Add(&bx, by.SetFloat64(y[i]))
+		if want, _ := bx.Float64(); r[i] != want {
+			t.Fatalf("Axpy2 element %d = %.17g, want %.17g", i, r[i], want)
+		}
+	}
+}
+
+func BenchmarkTwoSumSlice(b *testing.B) {
+	p := randSlice(1000)
+	q := randSlice(1000)
+	x := make([]float64, len(p))
+	y := make([]float64, len(p))
+	for i := 0; i < b.N; i++ {
+		accsum.TwoSumSlice(p, q, x, y)
+	}
+}
+
+func BenchmarkTwoSumLoop(b *testing.B) {
+	p := randSlice(1000)
+	q := randSlice(1000)
+	x := make([]float64, len(p))
+	y := make([]float64, len(p))
+	for i := 0; i < b.N; i++ {
+		for j, pj := range p {
+			x[j], y[j] = accsum.TwoSum(pj, q[j])
+		}
+	}
+}
+
+func BenchmarkTwoProductSlice(b *testing.B) {
+	p := randSlice(1000)
+	q := randSlice(1000)
+	x := make([]float64, len(p))
+	y := make([]float64, len(p))
+	for i := 0; i < b.N; i++ {
+		accsum.TwoProductSlice(p, q, x, y)
+	}
+}
+
+func BenchmarkTwoProductLoop(b *testing.B) {
+	p := randSlice(1000)
+	q := randSlice(1000)
+	x := make([]float64, len(p))
+	y := make([]float64, len(p))
+	for i := 0; i < b.N; i++ {
+		for j, pj := range p {
+			x[j], y[j] = accsum.TwoProduct(pj, q[j])
+		}
+	}
+}